@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/pkg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flakyMinRuns int
+	flakyLimit   int
+)
+
+// Command for 'analyze' action
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Analyze the stored certsuite usage",
+}
+
+var analyzeFlakyCmd = &cobra.Command{
+	Use:   "flaky",
+	Short: "Rank the certsuite test cases whose outcome flips on the same commit and OCP version",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := AnalyzeFlakyTests(flakyMinRuns, flakyLimit); err != nil {
+			log.Fatalf("Failed to analyze flaky tests: %v", err)
+		}
+	},
+}
+
+// AnalyzeFlakyTests prints the most flaky test cases as a table.
+func AnalyzeFlakyTests(minRuns, limit int) error {
+	store, err := pkg.ChooseDatabase()
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer func() {
+		if closeErr := store.Close(); closeErr != nil {
+			log.Printf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	ranked, err := pkg.AnalyzeFlakyTests(store, minRuns)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RANK\tSCORE\tPASSES\tFAILURES\tCONFIGURATIONS\tTEST")
+	for i, t := range ranked {
+		fmt.Fprintf(w, "%d\t%.2f\t%d\t%d\t%d\t%s\n", i+1, t.Score, t.Passes, t.Failures, t.Configurations, t.Name)
+	}
+	return w.Flush()
+}
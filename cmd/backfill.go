@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/config"
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/pkg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backfillFrom    string
+	backfillTo      string
+	backfillChunk   string
+	backfillRestart bool
+)
+
+// Command for 'backfill' action
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Fetch historical certsuite usage in chunks, resuming interrupted runs",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := BackfillCertsuiteUsage(backfillFrom, backfillTo, backfillChunk, backfillRestart); err != nil {
+			log.Fatalf("Failed to backfill certsuite usage: %v", err)
+		}
+		log.Println("Certsuite usage backfilled successfully")
+	},
+}
+
+// BackfillCertsuiteUsage fetches the Quay and DCI data between two dates chunk by chunk.
+// Without an end date the backfill stops at the start of the current day, so that
+// re-running it the same day resumes the same range.
+func BackfillCertsuiteUsage(from, to, chunkSize string, restart bool) error {
+	now := time.Now().UTC()
+	if to == "" {
+		to = now.Format("2006-01-02")
+	}
+	rng, err := pkg.ResolveWindow(config.Config{Since: from, Until: to}, now)
+	if err != nil {
+		return fmt.Errorf("error resolving backfill range: %w", err)
+	}
+	chunk, err := pkg.ParseChunk(chunkSize)
+	if err != nil {
+		return err
+	}
+
+	store, err := pkg.ChooseDatabase()
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer func() {
+		if closeErr := store.Close(); closeErr != nil {
+			log.Printf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	return pkg.Backfill(store, rng, chunk, restart)
+}
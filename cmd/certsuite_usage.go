@@ -2,16 +2,54 @@ package main
 
 import (
 	"fmt"
+	"log"
+	"time"
 
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/config"
 	"github.com/redhat-best-practices-for-k8s/certsuite-overview/pkg"
 )
 
-// FetchCertsuiteUsage integrates data from Quay and DCI.
-func FetchCertsuiteUsage() error {
-	if err := pkg.FetchQuayData(); err != nil {
+// FetchCertsuiteUsage integrates data from Quay and DCI for the configured window.
+// When incremental, each source resumes from its watermark instead, falling back to
+// the configured window until it has synced once.
+func FetchCertsuiteUsage(incremental bool) error {
+	window, err := pkg.ResolveWindow(config.AppConfig, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("error resolving sync window: %w", err)
+	}
+	log.Printf("Syncing certsuite usage for %s", window)
+
+	store, err := pkg.ChooseDatabase()
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer func() {
+		if closeErr := store.Close(); closeErr != nil {
+			log.Printf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	quayWindow, dciWindow := window, window
+	if incremental {
+		if quayWindow, err = pkg.IncrementalWindow(store, pkg.QuaySource, config.AppConfig.SyncOverlap, window); err != nil {
+			return err
+		}
+		if dciWindow, err = pkg.DciIncrementalWindow(store, config.AppConfig.SyncOverlap, window); err != nil {
+			return err
+		}
+	}
+
+	// Each source's sync is recorded in the sync_runs audit log
+	err = pkg.TrackSyncRun(store, pkg.QuaySource, version, func() (pkg.SyncResult, error) {
+		return pkg.FetchQuayData(store, quayWindow)
+	})
+	if err != nil {
 		return fmt.Errorf("error fetching Quay data: %w", err)
 	}
-	if err := pkg.FetchDciData(); err != nil {
+	err = pkg.TrackSyncRun(store, pkg.DciSource, version, func() (pkg.SyncResult, error) {
+		return pkg.FetchDciData(store, dciWindow)
+	})
+	if err != nil {
 		return fmt.Errorf("error fetching DCI data: %w", err)
 	}
 	return nil
@@ -8,13 +8,26 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 )
 
+// version is the certsuite-overview version, set at build time with
+// -ldflags "-X main.version=<version>".
+var version = "dev"
+
 // Command for 'fetch' action
 var fetchCmd = &cobra.Command{
 	Use:   "fetch",
 	Short: "Fetch certsuite usage from Quay and DCI",
 	Run: func(cmd *cobra.Command, args []string) {
+		// An explicit --days wins over SINCE/UNTIL coming from the environment
+		if cmd.Flags().Changed("days") {
+			config.AppConfig.Since, config.AppConfig.Until = "", ""
+			config.AppConfig.ExplicitNumDays = true
+		}
+
+		// Without an explicit window, from the flags or NUM_DAYS/SINCE/UNTIL, each source resumes from its watermark
+		incremental := config.AppConfig.Since == "" && config.AppConfig.Until == "" && !config.AppConfig.ExplicitNumDays
+
 		// Fetch data from Quay and DCI and store it in the database
-		if err := FetchCertsuiteUsage(); err != nil {
+		if err := FetchCertsuiteUsage(incremental); err != nil {
 			log.Fatalf("Failed to fetch certsuite usage: %v", err)
 		}
 		log.Println("Certsuite usage fetched successfully")
@@ -23,13 +36,48 @@ var fetchCmd = &cobra.Command{
 
 // Root command
 var rootCmd = &cobra.Command{
-	Use:   "certsuite-overview",
-	Short: "A CLI to interact with certsuite data",
+	Use:     "certsuite-overview",
+	Short:   "A CLI to interact with certsuite data",
+	Version: version,
 }
 
 func init() {
 	config.LoadConfig()
+
+	// The window flags default to the environment, so flags override NUM_DAYS/SINCE/UNTIL
+	fetchCmd.Flags().StringVar(&config.AppConfig.Since, "since", config.AppConfig.Since, "start date (YYYY-MM-DD) of the sync window")
+	fetchCmd.Flags().StringVar(&config.AppConfig.Until, "until", config.AppConfig.Until, "end date (YYYY-MM-DD, exclusive) of the sync window, defaults to now")
+	fetchCmd.Flags().IntVar(&config.AppConfig.NumDays, "days", config.AppConfig.NumDays, "number of days to sync when no since date is given")
+	fetchCmd.MarkFlagsMutuallyExclusive("since", "days")
+	fetchCmd.MarkFlagsMutuallyExclusive("until", "days")
 	rootCmd.AddCommand(fetchCmd)
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+
+	repairCmd.Flags().IntVar(&repairDays, "days", 90, "number of days of DCI jobs to recompute")
+	rootCmd.AddCommand(repairCmd)
+
+	backfillCmd.Flags().StringVar(&backfillFrom, "from", "", "first day (YYYY-MM-DD) to backfill")
+	backfillCmd.Flags().StringVar(&backfillTo, "to", "", "end day (YYYY-MM-DD, exclusive) of the backfill, defaults to today")
+	backfillCmd.Flags().StringVar(&backfillChunk, "chunk", "7d", "size of each backfill window, such as 7d, 2w or 36h")
+	backfillCmd.Flags().BoolVar(&backfillRestart, "restart", false, "ignore the saved progress and backfill the whole range again")
+	_ = backfillCmd.MarkFlagRequired("from")
+	rootCmd.AddCommand(backfillCmd)
+
+	runsListCmd.Flags().IntVar(&runsLimit, "limit", 20, "number of most recent sync runs to list")
+	runsCmd.AddCommand(runsListCmd)
+	rootCmd.AddCommand(runsCmd)
+
+	analyzeFlakyCmd.Flags().IntVar(&flakyMinRuns, "min-runs", 3, "minimum runs on one commit and OCP version to judge a test")
+	analyzeFlakyCmd.Flags().IntVar(&flakyLimit, "limit", 20, "number of flaky tests to list, 0 for all")
+	analyzeCmd.AddCommand(analyzeFlakyCmd)
+	rootCmd.AddCommand(analyzeCmd)
+
+	reportAdoptionCmd.Flags().IntVar(&adoptionWeeks, "weeks", 12, "number of weeks to report, including the current one")
+	reportAdoptionCmd.Flags().StringVarP(&adoptionOutput, "output", "o", "table", "output format: table or json")
+	reportCmd.AddCommand(reportAdoptionCmd)
+	rootCmd.AddCommand(reportCmd)
 }
 
 func main() {
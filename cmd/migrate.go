@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/pkg"
+	"github.com/spf13/cobra"
+)
+
+// Command for 'migrate' action
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the database schema migrations",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply every pending schema migration",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := withMigrator(func(m *pkg.Migrator) error {
+			applied, err := m.Up()
+			if err != nil {
+				return err
+			}
+			if len(applied) == 0 {
+				log.Println("Database schema is already up to date")
+				return nil
+			}
+			log.Printf("Applied schema migrations %v", applied)
+			return nil
+		})
+		if err != nil {
+			log.Fatalf("Failed to apply migrations: %v", err)
+		}
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the most recently applied schema migration",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := withMigrator(func(m *pkg.Migrator) error {
+			reverted, err := m.Down()
+			if err != nil {
+				return err
+			}
+			if reverted == 0 {
+				log.Println("No schema migration to revert")
+				return nil
+			}
+			log.Printf("Reverted schema migration %d", reverted)
+			return nil
+		})
+		if err != nil {
+			log.Fatalf("Failed to revert migration: %v", err)
+		}
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List the schema migrations and whether they are applied",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := withMigrator(func(m *pkg.Migrator) error {
+			statuses, err := m.Status()
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tSTATUS\tAPPLIED AT\tDESCRIPTION")
+			for _, s := range statuses {
+				state := "pending"
+				switch {
+				case !s.Known:
+					state = "unknown"
+				case s.Applied:
+					state = "applied"
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, state, s.AppliedAt, s.Description)
+			}
+			return w.Flush()
+		})
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+	},
+}
+
+// withMigrator runs fn against the configured database.
+func withMigrator(fn func(m *pkg.Migrator) error) error {
+	m, err := pkg.OpenMigrator()
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer func() {
+		if closeErr := m.Close(); closeErr != nil {
+			log.Printf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	return fn(m)
+}
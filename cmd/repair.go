@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/pkg"
+	"github.com/spf13/cobra"
+)
+
+var repairDays int
+
+// Command for 'repair' action
+var repairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Recompute stored DCI results from source and fix rows that do not match",
+	Run: func(cmd *cobra.Command, args []string) {
+		repaired, err := RepairCertsuiteUsage(repairDays)
+		if err != nil {
+			log.Fatalf("Failed to repair certsuite usage: %v", err)
+		}
+		log.Printf("Repaired %d DCI job rows", repaired)
+	},
+}
+
+// RepairCertsuiteUsage rewrites the DCI rows of the last days that differ from DCI.
+func RepairCertsuiteUsage(days int) (int, error) {
+	window, err := pkg.LastDays(days, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("error resolving repair window: %w", err)
+	}
+
+	store, err := pkg.ChooseDatabase()
+	if err != nil {
+		return 0, fmt.Errorf("error opening database: %w", err)
+	}
+	defer func() {
+		if closeErr := store.Close(); closeErr != nil {
+			log.Printf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	repaired, err := pkg.RepairDciData(store, window)
+	if err != nil {
+		return repaired, fmt.Errorf("error repairing DCI data: %w", err)
+	}
+	return repaired, nil
+}
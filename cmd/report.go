@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/pkg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	adoptionWeeks  int
+	adoptionOutput string
+)
+
+// Command for 'report' action
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report on the stored certsuite usage",
+}
+
+var reportAdoptionCmd = &cobra.Command{
+	Use:   "adoption",
+	Short: "Show how many DCI jobs ran each certsuite release per week and how fast partners moved off old releases",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ReportAdoption(adoptionWeeks, adoptionOutput); err != nil {
+			log.Fatalf("Failed to report release adoption: %v", err)
+		}
+	},
+}
+
+// ReportAdoption prints the certsuite release adoption of the last weeks as a table or JSON.
+func ReportAdoption(weeks int, output string) error {
+	if output != "table" && output != "json" {
+		return fmt.Errorf("unsupported output %q, expected table or json", output)
+	}
+	window, err := pkg.AdoptionWindow(weeks, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	store, err := pkg.ChooseDatabase()
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer func() {
+		if closeErr := store.Close(); closeErr != nil {
+			log.Printf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	report, err := pkg.GetAdoptionReport(store, window)
+	if err != nil {
+		return err
+	}
+	if output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return printAdoptionReport(os.Stdout, report)
+}
+
+// printAdoptionReport writes the release summary followed by the weekly job counts of
+// the releases run during the report, newest release first.
+func printAdoptionReport(out io.Writer, report pkg.AdoptionReport) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RELEASE\tFIRST SEEN\tLAST SEEN\tJOBS\tSUPERSEDED BY\tMOVE-OFF DAYS")
+	for _, r := range report.Releases {
+		moveOff := "-"
+		if r.MoveOffDays != nil {
+			moveOff = fmt.Sprint(*r.MoveOffDays)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", r.Release, r.FirstSeen, r.LastSeen, r.Jobs, r.SupersededBy, moveOff)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	var releases []string
+	for _, r := range report.Releases {
+		for _, week := range report.Weeks {
+			if week.Jobs[r.Release] > 0 {
+				releases = append(releases, r.Release)
+				break
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "\nDCI jobs per week from %s to %s\n", report.Since, report.Until)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := append(append([]string{"WEEK"}, releases...), "DEV", "QUAY PULLS")
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, week := range report.Weeks {
+		fmt.Fprintf(w, "%s\t", week.Week)
+		for _, release := range releases {
+			fmt.Fprintf(w, "%d\t", week.Jobs[release])
+		}
+		fmt.Fprintf(w, "%d\t%d\n", week.DevJobs, week.QuayPulls)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/pkg"
+	"github.com/spf13/cobra"
+)
+
+var runsLimit int
+
+// Command for 'runs' action
+var runsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "Inspect the audit log of sync runs",
+}
+
+var runsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the most recent sync runs",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ListSyncRuns(runsLimit); err != nil {
+			log.Fatalf("Failed to list sync runs: %v", err)
+		}
+	},
+}
+
+// ListSyncRuns prints the most recent sync runs as a table, newest first.
+func ListSyncRuns(limit int) error {
+	if limit <= 0 {
+		return fmt.Errorf("limit must be positive, got %d", limit)
+	}
+
+	store, err := pkg.ChooseDatabase()
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer func() {
+		if closeErr := store.Close(); closeErr != nil {
+			log.Printf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	runs, err := store.ListSyncRuns(limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSOURCE\tSTARTED AT\tDURATION\tSTATUS\tINSERTED\tUPDATED\tWRITTEN\tVERSION\tERROR")
+	for _, r := range runs {
+		status := "ok"
+		if r.Error != "" {
+			status = "failed"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\t%s\n", r.ID, r.Source, r.StartedAt.Format("2006-01-02 15:04:05"),
+			r.FinishedAt.Sub(r.StartedAt), status, r.RowsInserted, r.RowsUpdated, r.RowsWritten, r.Version, r.Error)
+	}
+	return w.Flush()
+}
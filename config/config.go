@@ -2,20 +2,57 @@ package config
 
 import (
 	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
+	DBChoice    string
+	DBPath      string
 	DBUser      string
 	DBPassword  string
 	DBURL       string
 	DBPort      string
+	DBSSLMode   string
 	ClientID    string
 	APISecret   string
 	BearerToken string
-	Namespace   string
-	Repository  string
+	// Namespace and Repository are the main certsuite image, whose pulls the adoption
+	// report counts. They default to the first of QuayRepositories.
+	Namespace  string
+	Repository string
+	// QuayRepositories lists the namespace/repository pairs of every tracked Quay image.
+	// It defaults to Namespace/Repository.
+	QuayRepositories []string
+	// QuayScanTags are the glob patterns of the tags whose vulnerability scans are recorded.
+	QuayScanTags []string
+	// QuayPullBreakdown enables counting Quay pulls by client type and region, read from
+	// GeoIPDatabase, an offline MaxMind database file, when it is set.
+	QuayPullBreakdown bool
+	GeoIPDatabase     string
+	// QuayStorePerformerNames keeps the names of the users and robots pulling from Quay
+	// in quay_pull_performers instead of only counting them by kind.
+	QuayStorePerformerNames bool
+	// Sync window: either Since/Until dates (YYYY-MM-DD) or the last NumDays days.
+	// ExplicitNumDays is set when NumDays was given rather than defaulted, which makes
+	// it the window instead of the fallback of incremental syncs.
+	NumDays         int
+	ExplicitNumDays bool
+	Since           string
+	Until           string
+	// SyncOverlap is how far before a source's watermark an incremental sync starts,
+	// to pick up late-arriving results.
+	SyncOverlap time.Duration
+	// AnonymizeSalt, when set, replaces DCI team and remoteci identifiers and names
+	// with hashes salted with it.
+	AnonymizeSalt string
+	// Glob patterns of the DCI result file names holding certsuite's results, and
+	// of the result files whose results are tracked per file.
+	CertsuiteResultFiles []string
+	TrackedResultFiles   []string
 }
 
 var AppConfig Config
@@ -27,15 +64,39 @@ func LoadConfig() {
 
 	// Load the configuration into the AppConfig struct
 	AppConfig = Config{
-		DBUser:      GetConfigValue("DB_USER"),
-		DBPassword:  GetConfigValue("DB_PASSWORD"),
-		DBURL:       GetConfigValue("DB_URL"),
-		DBPort:      GetConfigValue("DB_PORT"),
-		ClientID:    GetConfigValue("CLIENTID"),
-		APISecret:   GetConfigValue("APISECRET"),
-		BearerToken: GetConfigValue("BEARERTOKEN"),
-		Namespace:   GetConfigValue("NAMESPACE"),
-		Repository:  GetConfigValue("REPOSITORY"),
+		DBChoice:                GetOptionalConfigValue("DB_CHOICE", "local"),
+		DBPath:                  GetOptionalConfigValue("DB_PATH", "certsuite_usage.db"),
+		DBUser:                  GetOptionalConfigValue("DB_USER", ""),
+		DBPassword:              GetOptionalConfigValue("DB_PASSWORD", ""),
+		DBURL:                   GetOptionalConfigValue("DB_URL", ""),
+		DBPort:                  GetOptionalConfigValue("DB_PORT", ""),
+		DBSSLMode:               GetOptionalConfigValue("DB_SSLMODE", "require"),
+		ClientID:                GetConfigValue("CLIENTID"),
+		APISecret:               GetConfigValue("APISECRET"),
+		BearerToken:             GetConfigValue("BEARERTOKEN"),
+		Namespace:               GetOptionalConfigValue("NAMESPACE", ""),
+		Repository:              GetOptionalConfigValue("REPOSITORY", ""),
+		QuayRepositories:        GetOptionalListConfigValue("QUAY_REPOSITORIES", nil),
+		QuayScanTags:            GetOptionalListConfigValue("QUAY_SCAN_TAGS", []string{"latest", "v*"}),
+		QuayPullBreakdown:       GetOptionalBoolConfigValue("QUAY_PULL_BREAKDOWN", false),
+		GeoIPDatabase:           GetOptionalConfigValue("GEOIP_DATABASE", ""),
+		QuayStorePerformerNames: GetOptionalBoolConfigValue("QUAY_STORE_PERFORMER_NAMES", false),
+		NumDays:                 GetOptionalIntConfigValue("NUM_DAYS", 7),
+		ExplicitNumDays:         viper.GetString("NUM_DAYS") != "",
+		Since:                   GetOptionalConfigValue("SINCE", ""),
+		Until:                   GetOptionalConfigValue("UNTIL", ""),
+		SyncOverlap:             GetOptionalDurationConfigValue("SYNC_OVERLAP", 24*time.Hour),
+		AnonymizeSalt:           GetOptionalConfigValue("ANONYMIZE_SALT", ""),
+		CertsuiteResultFiles:    GetOptionalListConfigValue("DCI_CERTSUITE_RESULT_FILES", []string{"certsuite-tests_junit.xml"}),
+		TrackedResultFiles:      GetOptionalListConfigValue("DCI_RESULT_FILES", []string{"*"}),
+	}
+
+	// Either variable names the tracked Quay images, and the other is derived from it
+	switch {
+	case len(AppConfig.QuayRepositories) == 0:
+		AppConfig.QuayRepositories = []string{GetConfigValue("NAMESPACE") + "/" + GetConfigValue("REPOSITORY")}
+	case AppConfig.Namespace == "" || AppConfig.Repository == "":
+		AppConfig.Namespace, AppConfig.Repository, _ = strings.Cut(AppConfig.QuayRepositories[0], "/")
 	}
 }
 
@@ -46,4 +107,66 @@ func GetConfigValue(key string) string {
 		log.Fatalf("Configuration key %s is missing", key)
 	}
 	return value
-}
\ No newline at end of file
+}
+
+// Helper function to get an optional configuration value by key, falling back to defaultValue
+func GetOptionalConfigValue(key, defaultValue string) string {
+	value := viper.GetString(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// Helper function to get an optional integer configuration value by key, falling back to defaultValue
+func GetOptionalIntConfigValue(key string, defaultValue int) int {
+	value := viper.GetString(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Configuration key %s must be an integer, got %q", key, value)
+	}
+	return parsed
+}
+
+// Helper function to get an optional boolean configuration value by key, falling back to defaultValue
+func GetOptionalBoolConfigValue(key string, defaultValue bool) bool {
+	value := viper.GetString(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Configuration key %s must be a boolean, got %q", key, value)
+	}
+	return parsed
+}
+
+// Helper function to get an optional duration configuration value by key, falling back to defaultValue
+func GetOptionalDurationConfigValue(key string, defaultValue time.Duration) time.Duration {
+	value := viper.GetString(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Configuration key %s must be a duration such as 24h, got %q", key, value)
+	}
+	return parsed
+}
+
+// Helper function to get an optional comma-separated list configuration value by key, falling back to defaultValue
+func GetOptionalListConfigValue(key string, defaultValue []string) []string {
+	var values []string
+	for _, value := range strings.Split(viper.GetString(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
@@ -0,0 +1,195 @@
+package pkg
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/config"
+)
+
+const (
+	// quayPullKind is the Quay log kind of an image pull.
+	quayPullKind = "pull_repo"
+
+	// reportDateFormat is the layout of the dates in reports.
+	reportDateFormat = "2006-01-02"
+)
+
+// AdoptionReport shows how the certsuite releases were adopted by the DCI jobs of partners.
+type AdoptionReport struct {
+	Since string `json:"since"`
+	Until string `json:"until"`
+	// Releases lists every release tag ever run by a DCI job, newest first.
+	Releases []ReleaseAdoption `json:"releases"`
+	// Weeks lists the weeks of the report, oldest first.
+	Weeks []AdoptionWeek `json:"weeks"`
+}
+
+// ReleaseAdoption summarizes the DCI jobs that ran a certsuite release.
+type ReleaseAdoption struct {
+	Release   string `json:"release"`
+	FirstSeen string `json:"first_seen"`
+	LastSeen  string `json:"last_seen"`
+	Jobs      int    `json:"jobs"`
+	// SupersededBy is the next release that has run in DCI, if any.
+	SupersededBy string `json:"superseded_by,omitempty"`
+	// MoveOffDays is how many days jobs kept running the release after its successor
+	// was first seen, nil if it was not superseded.
+	MoveOffDays *int `json:"move_off_days,omitempty"`
+}
+
+// AdoptionWeek counts the DCI jobs of one week, starting on Monday, by certsuite release.
+type AdoptionWeek struct {
+	Week string         `json:"week"`
+	Jobs map[string]int `json:"jobs"`
+	// DevJobs counts the jobs that ran a dev build rather than a release.
+	DevJobs int `json:"dev_jobs"`
+	// QuayPulls is the number of certsuite image pulls from Quay during the week.
+	QuayPulls int `json:"quay_pulls"`
+}
+
+// weekStart returns the Monday starting the UTC week of t.
+func weekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// AdoptionWindow returns the window of the last weeks weeks, including the current one.
+func AdoptionWindow(weeks int, now time.Time) (Window, error) {
+	if weeks <= 0 {
+		return Window{}, fmt.Errorf("number of weeks must be positive, got %d", weeks)
+	}
+	since := weekStart(now).AddDate(0, 0, -7*(weeks-1))
+	return Window{Since: since, Until: weekStart(now).AddDate(0, 0, 7)}, nil
+}
+
+// GetAdoptionReport builds the adoption report of the window from the stored data.
+func GetAdoptionReport(store Store, window Window) (AdoptionReport, error) {
+	jobs, err := store.GetDciJobs()
+	if err != nil {
+		return AdoptionReport{}, fmt.Errorf("failed to read stored DCI jobs: %w", err)
+	}
+	aggregates, err := store.GetQuayAggregates()
+	if err != nil {
+		return AdoptionReport{}, fmt.Errorf("failed to read stored Quay aggregates: %w", err)
+	}
+	// Only the pulls of the certsuite image itself measure its adoption
+	var certsuitePulls []QuayAggregate
+	for _, aggregate := range aggregates {
+		if aggregate.Namespace == config.AppConfig.Namespace && aggregate.Repository == config.AppConfig.Repository {
+			certsuitePulls = append(certsuitePulls, aggregate)
+		}
+	}
+	return buildAdoptionReport(jobs, certsuitePulls, window), nil
+}
+
+// buildAdoptionReport counts the jobs of every week of the window by release, and
+// summarizes the whole history of every release so that move-off times are complete.
+func buildAdoptionReport(jobs []DciJob, aggregates []QuayAggregate, window Window) AdoptionReport {
+	report := AdoptionReport{
+		Since: window.Since.Format(reportDateFormat),
+		Until: window.Until.Format(reportDateFormat),
+	}
+
+	weeks := map[string]*AdoptionWeek{}
+	for week := weekStart(window.Since); week.Before(window.Until); week = week.AddDate(0, 0, 7) {
+		w := AdoptionWeek{Week: week.Format(reportDateFormat), Jobs: map[string]int{}}
+		report.Weeks = append(report.Weeks, w)
+	}
+	for i := range report.Weeks {
+		weeks[report.Weeks[i].Week] = &report.Weeks[i]
+	}
+
+	releases := map[string]*ReleaseAdoption{}
+	for _, job := range jobs {
+		createdAt, err := time.Parse(dciTimeFormat, job.CreatedAt)
+		if err != nil {
+			log.Printf("Skipping DCI job %s with invalid creation date %q: %v", job.JobID, job.CreatedAt, err)
+			continue
+		}
+		day := createdAt.Format(reportDateFormat)
+
+		// DCI reports the same release as v5.2.1 or 5.2.1
+		version := normalizeVersion(job.CertsuiteVersion)
+		if job.IsRelease {
+			release, ok := releases[version]
+			if !ok {
+				release = &ReleaseAdoption{Release: version, FirstSeen: day, LastSeen: day}
+				releases[version] = release
+			}
+			release.Jobs++
+			if day < release.FirstSeen {
+				release.FirstSeen = day
+			}
+			if day > release.LastSeen {
+				release.LastSeen = day
+			}
+		}
+
+		if !window.Contains(createdAt) {
+			continue
+		}
+		week := weeks[weekStart(createdAt).Format(reportDateFormat)]
+		if job.IsRelease {
+			week.Jobs[version]++
+		} else {
+			week.DevJobs++
+		}
+	}
+
+	for _, aggregate := range aggregates {
+		day, err := time.Parse(reportDateFormat, aggregate.Datetime)
+		if err != nil || aggregate.Kind != quayPullKind || !window.Contains(day) {
+			continue
+		}
+		weeks[weekStart(day).Format(reportDateFormat)].QuayPulls += aggregate.Count
+	}
+
+	for _, release := range releases {
+		report.Releases = append(report.Releases, *release)
+	}
+	sort.Slice(report.Releases, func(i, j int) bool {
+		return compareVersions(report.Releases[i].Release, report.Releases[j].Release) > 0
+	})
+	// Each release is superseded by the next newer release that has run
+	for i := 1; i < len(report.Releases); i++ {
+		older, newer := &report.Releases[i], report.Releases[i-1]
+		older.SupersededBy = newer.Release
+		lastSeen, _ := time.Parse(reportDateFormat, older.LastSeen)
+		successorSeen, _ := time.Parse(reportDateFormat, newer.FirstSeen)
+		days := max(0, int(lastSeen.Sub(successorSeen).Hours()/24))
+		older.MoveOffDays = &days
+	}
+	return report
+}
+
+// normalizeVersion returns a semantic version with the v prefix of the certsuite release tags.
+func normalizeVersion(version string) string {
+	return "v" + strings.TrimPrefix(version, "v")
+}
+
+// compareVersions orders two semantic versions such as v5.2.1, returning a negative
+// number when a is older than b, 0 when they are equal and a positive number otherwise.
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := range pa {
+		if pa[i] != pb[i] {
+			return pa[i] - pb[i]
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// versionParts returns the major, minor and patch numbers of a semantic version.
+func versionParts(version string) [3]int {
+	var parts [3]int
+	core, _, _ := strings.Cut(strings.TrimPrefix(version, "v"), "-")
+	for i, part := range strings.SplitN(core, ".", 3) {
+		parts[i], _ = strconv.Atoi(part)
+	}
+	return parts
+}
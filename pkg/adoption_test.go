@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdoptionWindow(t *testing.T) {
+	// Wednesday 2024-11-27
+	window, err := AdoptionWindow(2, time.Date(2024, 11, 27, 15, 0, 0, 0, time.UTC))
+
+	require.NoError(t, err)
+	assert.Equal(t, Window{
+		Since: time.Date(2024, 11, 18, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2024, 12, 2, 0, 0, 0, 0, time.UTC),
+	}, window)
+
+	_, err = AdoptionWindow(0, time.Now())
+	assert.Error(t, err)
+}
+
+func TestBuildAdoptionReport(t *testing.T) {
+	jobs := []DciJob{
+		{JobID: "job-1", CreatedAt: "2024-10-01T10:00:00", CertsuiteVersion: "v5.1.0", IsRelease: true},
+		{JobID: "job-2", CreatedAt: "2024-11-19T10:00:00", CertsuiteVersion: "v5.1.0", IsRelease: true},
+		{JobID: "job-3", CreatedAt: "2024-11-12T10:00:00", CertsuiteVersion: "v5.10.0", IsRelease: true},
+		{JobID: "job-4", CreatedAt: "2024-11-20T10:00:00", CertsuiteVersion: "v5.10.0", IsRelease: true},
+		// The same release without its v prefix
+		{JobID: "job-5", CreatedAt: "2024-11-26T10:00:00", CertsuiteVersion: "5.10.0", IsRelease: true},
+		{JobID: "job-6", CreatedAt: "2024-11-27T10:00:00", CommitHash: "0a1b2c3d", CertsuiteVersion: "v5.10.0"},
+	}
+	aggregates := []QuayAggregate{
+		{Datetime: "2024-11-18", Count: 40, Kind: "pull_repo"},
+		{Datetime: "2024-11-24", Count: 2, Kind: "pull_repo"},
+		{Datetime: "2024-11-25", Count: 7, Kind: "pull_repo"},
+		{Datetime: "2024-11-25", Count: 1, Kind: "push_repo"},
+		{Datetime: "2024-11-11", Count: 100, Kind: "pull_repo"},
+	}
+	window := Window{
+		Since: time.Date(2024, 11, 18, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2024, 12, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	report := buildAdoptionReport(jobs, aggregates, window)
+
+	sevenDays := 7
+	assert.Equal(t, AdoptionReport{
+		Since: "2024-11-18",
+		Until: "2024-12-02",
+		Releases: []ReleaseAdoption{
+			{Release: "v5.10.0", FirstSeen: "2024-11-12", LastSeen: "2024-11-26", Jobs: 3},
+			{Release: "v5.1.0", FirstSeen: "2024-10-01", LastSeen: "2024-11-19", Jobs: 2, SupersededBy: "v5.10.0", MoveOffDays: &sevenDays},
+		},
+		Weeks: []AdoptionWeek{
+			{Week: "2024-11-18", Jobs: map[string]int{"v5.1.0": 1, "v5.10.0": 1}, QuayPulls: 42},
+			{Week: "2024-11-25", Jobs: map[string]int{"v5.10.0": 1}, DevJobs: 1, QuayPulls: 7},
+		},
+	}, report)
+}
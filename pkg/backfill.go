@@ -0,0 +1,147 @@
+package pkg
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/config"
+	dci "github.com/sebrandon1/go-dci/lib"
+)
+
+// ParseChunk parses a backfill chunk size such as 7d, 2w or 36h.
+// Quay reports whole days, so a chunk must span at least one day.
+func ParseChunk(s string) (time.Duration, error) {
+	var chunk time.Duration
+	switch {
+	case strings.HasSuffix(s, "d"), strings.HasSuffix(s, "w"):
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid chunk size %q: %w", s, err)
+		}
+		chunk = time.Duration(n) * 24 * time.Hour
+		if strings.HasSuffix(s, "w") {
+			chunk *= 7
+		}
+	default:
+		var err error
+		if chunk, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid chunk size %q: %w", s, err)
+		}
+	}
+	if chunk < 24*time.Hour {
+		return 0, fmt.Errorf("chunk size %q must be at least one day", s)
+	}
+	return chunk, nil
+}
+
+// Backfill fetches the Quay and DCI data of rng chunk by chunk, recording its
+// progress after every chunk so an interrupted backfill resumes where it stopped.
+// With restart set, any previous progress for the same range is ignored.
+// DCI can only page back from now, so its jobs are fetched once for the rest of the
+// range and split into chunks locally. The Quay tag and vulnerability snapshots only
+// reflect the current state, so a backfill does not take them.
+func Backfill(store Store, rng Window, chunk time.Duration, restart bool) error {
+	opts, err := newDciOptions(config.AppConfig)
+	if err != nil {
+		return err
+	}
+	dciClient := dci.NewClient(config.AppConfig.ClientID, config.AppConfig.APISecret)
+
+	var runs []dci.JobsResponse
+	fetchedRuns := false
+	return backfill(store, rng, chunk, restart, func(store Store, window Window) error {
+		if _, err := fetchQuayData(store, window, false); err != nil {
+			return fmt.Errorf("error fetching Quay data: %w", err)
+		}
+		// The first chunk starts where the backfill resumes, so the runs cover every chunk left
+		if !fetchedRuns {
+			var err error
+			if runs, err = fetchDciRuns(dciClient, Window{Since: window.Since, Until: rng.Until}); err != nil {
+				return fmt.Errorf("error fetching DCI data: %w", err)
+			}
+			fetchedRuns = true
+		}
+		if _, err := storeDciData(store, dciClient, runs, window, opts); err != nil {
+			return fmt.Errorf("error storing DCI data: %w", err)
+		}
+		return nil
+	})
+}
+
+// backfill walks rng in chunks of the given size, calling fetch for each of them.
+func backfill(store Store, rng Window, chunk time.Duration, restart bool, fetch func(Store, Window) error) error {
+	start := rng.Since
+	if !restart {
+		completedUntil, found, err := store.GetBackfillCheckpoint(rng)
+		if err != nil {
+			return fmt.Errorf("failed to read backfill checkpoint: %w", err)
+		}
+		if found {
+			log.Printf("Resuming backfill of %s from %s", rng, completedUntil.Format(time.RFC3339))
+			start = completedUntil
+		}
+	}
+
+	if !start.Before(rng.Until) {
+		log.Printf("Backfill of %s is already complete", rng)
+		return nil
+	}
+
+	for start.Before(rng.Until) {
+		end := start.Add(chunk)
+		if end.After(rng.Until) {
+			end = rng.Until
+		}
+		window := Window{Since: start, Until: end}
+
+		log.Printf("Backfilling %s", window)
+		if err := fetch(store, window); err != nil {
+			return fmt.Errorf("failed to backfill %s: %w", window, err)
+		}
+		if err := store.SaveBackfillCheckpoint(rng, end); err != nil {
+			return fmt.Errorf("failed to save backfill checkpoint: %w", err)
+		}
+		start = end
+	}
+
+	log.Printf("Backfill of %s completed", rng)
+	return nil
+}
+
+func (s *sqlStore) GetBackfillCheckpoint(rng Window) (time.Time, bool, error) {
+	query := s.dialect.rebind(`
+        SELECT completed_until FROM backfill_checkpoints
+        WHERE range_since = ? AND range_until = ?;`)
+
+	var completedUntil dbTime
+	err := s.db.QueryRow(query, rng.Since.UTC().Format(dbTimestampFormat), rng.Until.UTC().Format(dbTimestampFormat)).
+		Scan(&completedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return completedUntil.UTC(), true, nil
+}
+
+func (s *sqlStore) SaveBackfillCheckpoint(rng Window, completedUntil time.Time) error {
+	query := s.dialect.upsertQuery("backfill_checkpoints",
+		[]string{"range_since", "range_until", "completed_until", "updated_at"},
+		[]string{"range_since", "range_until"},
+		"completed_until = "+s.dialect.excluded("completed_until"),
+		"updated_at = "+s.dialect.excluded("updated_at"),
+	)
+	_, err := s.db.Exec(query,
+		rng.Since.UTC().Format(dbTimestampFormat),
+		rng.Until.UTC().Format(dbTimestampFormat),
+		completedUntil.UTC().Format(dbTimestampFormat),
+		time.Now().UTC().Format(dbTimestampFormat),
+	)
+	return err
+}
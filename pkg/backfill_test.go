@@ -0,0 +1,108 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChunk(t *testing.T) {
+	tests := []struct {
+		input         string
+		expected      time.Duration
+		expectedError bool
+	}{
+		{input: "7d", expected: 7 * 24 * time.Hour},
+		{input: "2w", expected: 14 * 24 * time.Hour},
+		{input: "36h", expected: 36 * time.Hour},
+		{input: "12h", expectedError: true},
+		{input: "xd", expectedError: true},
+		{input: "", expectedError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			chunk, err := ParseChunk(tc.input)
+
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, chunk)
+		})
+	}
+}
+
+func TestBackfillResumesFromCheckpoint(t *testing.T) {
+	store := newSQLiteStore(t)
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	rng := Window{Since: day(1), Until: day(20)}
+	chunk := 7 * 24 * time.Hour
+
+	// The second chunk fails, leaving only the first one recorded.
+	var fetched []Window
+	err := backfill(store, rng, chunk, false, func(_ Store, w Window) error {
+		if len(fetched) == 1 {
+			return errors.New("network down")
+		}
+		fetched = append(fetched, w)
+		return nil
+	})
+	require.Error(t, err)
+	assert.Equal(t, []Window{{Since: day(1), Until: day(8)}}, fetched)
+
+	// The next run resumes after the first chunk and stops at the end of the range.
+	fetched = nil
+	record := func(_ Store, w Window) error {
+		fetched = append(fetched, w)
+		return nil
+	}
+	require.NoError(t, backfill(store, rng, chunk, false, record))
+	assert.Equal(t, []Window{
+		{Since: day(8), Until: day(15)},
+		{Since: day(15), Until: day(20)},
+	}, fetched)
+
+	// A completed range is not fetched again unless restarted.
+	fetched = nil
+	require.NoError(t, backfill(store, rng, chunk, false, record))
+	assert.Empty(t, fetched)
+	require.NoError(t, backfill(store, rng, chunk, true, record))
+	assert.Len(t, fetched, 3)
+}
+
+func TestStoreDciDataSplitsRunsByChunk(t *testing.T) {
+	// The runs fetched once for the whole backfill are stored chunk by chunk
+	runs := dciRunsFromJSON(t, `{"jobs": [
+		{
+			"id": "job-1",
+			"created_at": "2024-11-05T12:00:00.000000",
+			"components": [{"name": "certsuite abc123"}],
+			"results": [{"name": "certsuite-tests_junit.xml", "success": 1}]
+		},
+		{
+			"id": "job-2",
+			"created_at": "2024-11-20T12:00:00.000000",
+			"components": [{"name": "certsuite def456"}],
+			"results": [{"name": "certsuite-tests_junit.xml", "success": 2}]
+		}
+	]}`)
+	store := newSQLiteStore(t)
+	chunks := []Window{
+		{Since: novemberWindow.Since, Until: time.Date(2024, 11, 15, 0, 0, 0, 0, time.UTC)},
+		{Since: time.Date(2024, 11, 15, 0, 0, 0, 0, time.UTC), Until: novemberWindow.Until},
+	}
+
+	for i, chunk := range chunks {
+		result, err := storeDciData(store, nil, runs, chunk, testDciOptions)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Inserted, "chunk %d", i)
+	}
+	jobs, err := store.GetDciJobs()
+	require.NoError(t, err)
+	assert.Len(t, jobs, 2)
+}
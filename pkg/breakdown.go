@@ -0,0 +1,200 @@
+package pkg
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+	quay "github.com/sebrandon1/go-quay/lib"
+	"github.com/sirupsen/logrus"
+)
+
+// Client types of Quay pulls.
+const (
+	PullClientPodman  = "podman"
+	PullClientDocker  = "docker"
+	PullClientSkopeo  = "skopeo"
+	PullClientCRIO    = "cri-o" // CRI-O, containerd and other kubelet runtimes.
+	PullClientDCI     = "dci-agent"
+	PullClientOther   = "other"
+	unknownPullRegion = "unknown"
+)
+
+// QuayPullBreakdown is the number of pulls of a Quay repository on one day by one
+// client type from one region. IP addresses are never stored.
+type QuayPullBreakdown struct {
+	Datetime   string // Day of the pulls, formatted as YYYY-MM-DD.
+	Namespace  string
+	Repository string
+	Client     string // One of the PullClient constants.
+	// Region is the continent code of the pulling address, such as EU or NA, or unknown.
+	Region string
+	Count  int
+}
+
+// pullClient classifies a pull by the user agent and performer of its log entry.
+func pullClient(entry quay.LogEntry) string {
+	userAgent := strings.ToLower(entry.Metadata.UserAgent)
+	switch {
+	// DCI agents pull with podman or skopeo, but from DCI robot accounts or with DCI in their user agent
+	case strings.Contains(userAgent, "dci") || strings.Contains(strings.ToLower(entry.Performer.Name), "dci"):
+		return PullClientDCI
+	case strings.Contains(userAgent, "skopeo"):
+		return PullClientSkopeo
+	case strings.Contains(userAgent, "cri-o") || strings.Contains(userAgent, "containerd") || strings.Contains(userAgent, "kubelet"):
+		return PullClientCRIO
+	case strings.Contains(userAgent, "libpod") || strings.Contains(userAgent, "podman"):
+		return PullClientPodman
+	case strings.Contains(userAgent, "docker/"):
+		return PullClientDocker
+	default:
+		return PullClientOther
+	}
+}
+
+// regionLocator returns the continent code of an IP address, or an empty string if it is unknown.
+type regionLocator func(ip net.IP) string
+
+// openGeoIPLocator returns a locator reading the MaxMind database at path, and a function closing it.
+func openGeoIPLocator(path string) (regionLocator, func(), error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open GeoIP database %s: %w", path, err)
+	}
+	locate := func(ip net.IP) string {
+		var record struct {
+			Continent struct {
+				Code string `maxminddb:"code"`
+			} `maxminddb:"continent"`
+		}
+		if err := reader.Lookup(ip, &record); err != nil {
+			return ""
+		}
+		return record.Continent.Code
+	}
+	closeReader := func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Failed to close GeoIP database %s: %v", path, err)
+		}
+	}
+	return locate, closeReader, nil
+}
+
+// pullRegion returns the continent of a pull from the GeoIP database if there is one,
+// falling back to the continent Quay resolved.
+func pullRegion(entry quay.LogEntry, locate regionLocator) string {
+	if ip := net.ParseIP(entry.IP); ip != nil && locate != nil {
+		if region := locate(ip); region != "" {
+			return region
+		}
+	}
+	if entry.Metadata.ResolvedIP.Continent != "" {
+		return strings.ToUpper(entry.Metadata.ResolvedIP.Continent)
+	}
+	return unknownPullRegion
+}
+
+// quayPullBreakdown counts the pulls of a repository by day, client type and region,
+// ordered by day, client and region.
+func quayPullBreakdown(repository QuayRepository, pulls []quay.LogEntry, locate regionLocator) ([]QuayPullBreakdown, error) {
+	counts := map[QuayPullBreakdown]int{}
+	for _, entry := range pulls {
+		pulledAt, err := time.Parse(quayDatetimeFormat, entry.Datetime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Quay datetime format: %v", entry.Datetime)
+		}
+		key := QuayPullBreakdown{
+			Datetime:   pulledAt.UTC().Format("2006-01-02"),
+			Namespace:  repository.Namespace,
+			Repository: repository.Name,
+			Client:     pullClient(entry),
+			Region:     pullRegion(entry, locate),
+		}
+		counts[key]++
+	}
+
+	breakdown := make([]QuayPullBreakdown, 0, len(counts))
+	for key, count := range counts {
+		key.Count = count
+		breakdown = append(breakdown, key)
+	}
+	sort.Slice(breakdown, func(i, j int) bool {
+		a, b := breakdown[i], breakdown[j]
+		if a.Datetime != b.Datetime {
+			return a.Datetime < b.Datetime
+		}
+		if a.Client != b.Client {
+			return a.Client < b.Client
+		}
+		return a.Region < b.Region
+	})
+	return breakdown, nil
+}
+
+// storeQuayPullBreakdown replaces the pull counts by client and region of a repository on the
+// days from since to until, both inclusive, which the pulls were read for.
+func storeQuayPullBreakdown(store Store, repository QuayRepository, since, until string, breakdown []QuayPullBreakdown) (int, error) {
+	if err := store.ReplaceQuayPullBreakdown(repository, since, until, breakdown); err != nil {
+		return 0, fmt.Errorf("failed to store pulls of %s by client and region from %s to %s: %w", repository, since, until, err)
+	}
+	log.Printf("Stored %d daily pull counts by client and region", len(breakdown))
+	return len(breakdown), nil
+}
+
+func (s *sqlStore) ReplaceQuayPullBreakdown(repository QuayRepository, since, until string, breakdown []QuayPullBreakdown) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	rollback := func(err error) error {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logrus.Errorf("failed to roll back pulls by client and region of %s: %v", repository, rbErr)
+		}
+		return err
+	}
+
+	// Clients and regions no longer found in the logs of the synced days must not be kept
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM quay_pull_breakdown
+		WHERE namespace = ? AND repository = ? AND datetime >= ? AND datetime <= ?;`),
+		repository.Namespace, repository.Name, since, until); err != nil {
+		return rollback(err)
+	}
+	insert := s.dialect.rebind(`INSERT INTO quay_pull_breakdown (datetime, namespace, repository, client, region, count)
+		VALUES (?, ?, ?, ?, ?, ?);`)
+	for _, b := range breakdown {
+		if _, err := tx.Exec(insert, b.Datetime, b.Namespace, b.Repository, b.Client, b.Region, b.Count); err != nil {
+			return rollback(err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetQuayPullBreakdown returns every stored pull count by client and region, ordered by day, repository, client and region.
+func (s *sqlStore) GetQuayPullBreakdown() ([]QuayPullBreakdown, error) {
+	rows, err := s.db.Query(`SELECT datetime, namespace, repository, client, region, count FROM quay_pull_breakdown
+		ORDER BY datetime, namespace, repository, client, region;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quay_pull_breakdown: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close quay_pull_breakdown rows: %v", err)
+		}
+	}()
+
+	var breakdown []QuayPullBreakdown
+	for rows.Next() {
+		var b QuayPullBreakdown
+		var datetime dbTime
+		if err := rows.Scan(&datetime, &b.Namespace, &b.Repository, &b.Client, &b.Region, &b.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan quay_pull_breakdown row: %w", err)
+		}
+		b.Datetime = datetime.Format("2006-01-02")
+		breakdown = append(breakdown, b)
+	}
+	return breakdown, rows.Err()
+}
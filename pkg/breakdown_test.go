@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	quay "github.com/sebrandon1/go-quay/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullClient(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		performer string
+		expected  string
+	}{
+		{"libpod/4.9.4 go/go1.21.9 os/linux arch/amd64", "", PullClientPodman},
+		{"docker/24.0.7 go/go1.20.10 git-commit/311b9ff kernel/6.5.0 os/linux arch/amd64", "", PullClientDocker},
+		{"skopeo/1.14.2", "", PullClientSkopeo},
+		{"cri-o/1.29.1 go/go1.21.7 os/linux arch/amd64", "", PullClientCRIO},
+		{"containerd/1.7.13", "", PullClientCRIO},
+		{"libpod/4.9.4 go/go1.21.9 os/linux arch/amd64", "redhat+dci_agent", PullClientDCI},
+		{"dci-pipeline/0.9 skopeo/1.14.2", "", PullClientDCI},
+		{"curl/8.5.0", "", PullClientOther},
+		{"", "", PullClientOther},
+	}
+
+	for _, tc := range tests {
+		entry := quay.LogEntry{Metadata: quay.Metadata{UserAgent: tc.userAgent}, Performer: quay.Performer{Name: tc.performer}}
+		assert.Equal(t, tc.expected, pullClient(entry), "user agent %q, performer %q", tc.userAgent, tc.performer)
+	}
+}
+
+func TestQuayPullBreakdown(t *testing.T) {
+	europe := net.ParseIP("192.0.2.1")
+	locate := func(ip net.IP) string {
+		if ip.Equal(europe) {
+			return "EU"
+		}
+		return ""
+	}
+	pulls := []quay.LogEntry{
+		{Datetime: "Tue, 26 Nov 2024 10:00:00 -0000", IP: "192.0.2.1", Metadata: quay.Metadata{UserAgent: "libpod/4.9.4"}},
+		{Datetime: "Tue, 26 Nov 2024 11:00:00 -0000", IP: "192.0.2.1", Metadata: quay.Metadata{UserAgent: "libpod/5.0.0"}},
+		// Unknown to the GeoIP database, so located by Quay
+		{Datetime: "Tue, 26 Nov 2024 12:00:00 -0000", IP: "198.51.100.7",
+			Metadata: quay.Metadata{UserAgent: "cri-o/1.29.1", ResolvedIP: quay.ResolvedIP{Continent: "na"}}},
+		{Datetime: "Wed, 27 Nov 2024 09:00:00 -0000", Metadata: quay.Metadata{UserAgent: "skopeo/1.14.2"}},
+	}
+
+	breakdown, err := quayPullBreakdown(certsuiteRepository, pulls, locate)
+	require.NoError(t, err)
+	assert.Equal(t, []QuayPullBreakdown{
+		{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Client: PullClientCRIO, Region: "NA", Count: 1},
+		{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Client: PullClientPodman, Region: "EU", Count: 2},
+		{Datetime: "2024-11-27", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Client: PullClientSkopeo, Region: unknownPullRegion, Count: 1},
+	}, breakdown)
+
+	// Without a GeoIP database every pull is located by Quay, if at all
+	breakdown, err = quayPullBreakdown(certsuiteRepository, pulls[:1], nil)
+	require.NoError(t, err)
+	assert.Equal(t, unknownPullRegion, breakdown[0].Region)
+
+	store := newSQLiteStore(t)
+	written, err := storeQuayPullBreakdown(store, certsuiteRepository, "2024-11-26", "2024-11-27", breakdown)
+	require.NoError(t, err)
+	assert.Equal(t, len(breakdown), written)
+	stored, err := store.GetQuayPullBreakdown()
+	require.NoError(t, err)
+	assert.Equal(t, breakdown, stored)
+
+	// A re-sync of the same days drops the clients and regions it no longer reads pulls of
+	resynced := []QuayPullBreakdown{{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite",
+		Client: PullClientPodman, Region: "EU", Count: 3}}
+	_, err = storeQuayPullBreakdown(store, certsuiteRepository, "2024-11-26", "2024-11-27", resynced)
+	require.NoError(t, err)
+	stored, err = store.GetQuayPullBreakdown()
+	require.NoError(t, err)
+	assert.Equal(t, resynced, stored)
+}
+
+func TestOpenGeoIPLocatorInvalidDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GeoLite2-Country.mmdb")
+	require.NoError(t, os.WriteFile(path, []byte("not a MaxMind database"), 0o600))
+
+	_, _, err := openGeoIPLocator(path)
+	assert.ErrorContains(t, err, "failed to open GeoIP database")
+}
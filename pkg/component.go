@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"regexp"
+	"strings"
+
+	dci "github.com/sebrandon1/go-dci/lib"
+)
+
+// certsuiteProducts are the names under which certsuite appears in DCI components;
+// cnf-certification-test is the name of the project before its rename.
+var certsuiteProducts = []string{"certsuite", "cnf-certification-test"}
+
+var (
+	// describePattern matches a git describe build such as v5.2.1-3-gabc1234.
+	describePattern = regexp.MustCompile(`^(v?\d+\.\d+\.\d+)-\d+-g([0-9a-f]{7,40})$`)
+	// semverPattern matches a semantic version, with an optional pre-release suffix.
+	semverPattern = regexp.MustCompile(`^v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?$`)
+	// commitPattern matches an abbreviated or full commit SHA.
+	commitPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+)
+
+// CertsuiteComponent is the certsuite build a DCI job ran, parsed from its component metadata.
+type CertsuiteComponent struct {
+	Product string
+	// Version is the semantic version or tag of the build, empty if it has none.
+	Version string
+	// Commit is the commit SHA of the build, empty if unknown.
+	Commit string
+	// IsRelease is true for builds of a released version, false for dev builds.
+	IsRelease bool
+}
+
+// parseCertsuiteComponent parses a DCI component, reporting false if it is not certsuite.
+// The build is read from the component version, from the tag or suffix of the product,
+// and from the words following it in the name, such as "certsuite v5.2.1",
+// "quay.io/redhat-best-practices-for-k8s/certsuite:v5.2.1" or "cnf-certification-test 0a1b2c3".
+func parseCertsuiteComponent(component dci.Components) (CertsuiteComponent, bool) {
+	fields := strings.Fields(component.Name)
+	var product, productRef string
+	if len(fields) > 0 {
+		product, productRef = certsuiteProduct(fields[0])
+		fields = fields[1:]
+	}
+	if product == "" {
+		product, productRef = certsuiteProduct(component.Type)
+	}
+	if product == "" {
+		return CertsuiteComponent{}, false
+	}
+
+	parsed := CertsuiteComponent{Product: product}
+	refs := append([]string{component.Version, component.Data.Version, productRef}, fields...)
+	for _, ref := range refs {
+		parsed.addRef(strings.TrimSpace(ref))
+	}
+	return parsed, true
+}
+
+// certsuiteProduct returns the certsuite product named by s, or an empty string if s
+// does not name certsuite. s may be an image reference, whose registry and organization
+// are ignored, and the product may be followed by a build reference, as in certsuite:v5.2.1
+// or certsuite-v5.2.1, which is returned as ref. Image digests are dropped.
+func certsuiteProduct(s string) (string, string) {
+	name := s[strings.LastIndex(s, "/")+1:]
+	name, _, _ = strings.Cut(name, "@")
+	name, ref, _ := strings.Cut(name, ":")
+	for _, product := range certsuiteProducts {
+		switch lower := strings.ToLower(name); {
+		case lower == product:
+			return product, ref
+		case ref == "" && len(lower) > len(product)+1 && strings.HasPrefix(lower, product) && strings.ContainsRune("-_", rune(lower[len(product)])):
+			// Only a build reference may follow, so names like certsuite-collector are other products
+			if suffix := name[len(product)+1:]; isBuildRef(suffix) {
+				return product, suffix
+			}
+		}
+	}
+	return "", ""
+}
+
+// isBuildRef reports whether ref is a version, a git describe build or a commit SHA.
+func isBuildRef(ref string) bool {
+	return semverPattern.MatchString(ref) || describePattern.MatchString(ref) || commitPattern.MatchString(ref)
+}
+
+// addRef fills the version and commit the component still lacks from a build reference.
+func (c *CertsuiteComponent) addRef(ref string) {
+	if ref == "" {
+		return
+	}
+	switch {
+	case describePattern.MatchString(ref):
+		m := describePattern.FindStringSubmatch(ref)
+		if c.Version == "" {
+			c.Version, c.IsRelease = m[1], false
+		}
+		if c.Commit == "" {
+			c.Commit = m[2]
+		}
+	case semverPattern.MatchString(ref):
+		if c.Version == "" {
+			// Pre-release versions such as v5.2.1-rc1 are not releases
+			c.Version, c.IsRelease = ref, semverPattern.FindStringSubmatch(ref)[1] == ""
+		}
+	case commitPattern.MatchString(ref):
+		if c.Commit == "" {
+			c.Commit = ref
+		}
+	default:
+		// Tags such as "latest" or "unstable" name a build but not a release
+		if c.Version == "" {
+			c.Version = ref
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package pkg
+
+import (
+	"testing"
+
+	dci "github.com/sebrandon1/go-dci/lib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCertsuiteComponent(t *testing.T) {
+	tests := []struct {
+		name       string
+		component  dci.Components
+		expected   CertsuiteComponent
+		expectedOK bool
+	}{
+		{
+			name:       "Release",
+			component:  dci.Components{Name: "certsuite v5.2.1"},
+			expected:   CertsuiteComponent{Product: "certsuite", Version: "v5.2.1", IsRelease: true},
+			expectedOK: true,
+		},
+		{
+			name:       "Pre-release",
+			component:  dci.Components{Name: "certsuite", Version: "v5.3.0-rc1"},
+			expected:   CertsuiteComponent{Product: "certsuite", Version: "v5.3.0-rc1"},
+			expectedOK: true,
+		},
+		{
+			name:       "Dev build described from a release",
+			component:  dci.Components{Name: "certsuite v5.2.1-3-g0a1b2c3d"},
+			expected:   CertsuiteComponent{Product: "certsuite", Version: "v5.2.1", Commit: "0a1b2c3d"},
+			expectedOK: true,
+		},
+		{
+			name:       "Dev build of a commit under the old project name",
+			component:  dci.Components{Name: "cnf-certification-test 9f8e7d6c5b4a"},
+			expected:   CertsuiteComponent{Product: "cnf-certification-test", Commit: "9f8e7d6c5b4a"},
+			expectedOK: true,
+		},
+		{
+			name:       "Component type with an organization prefix",
+			component:  dci.Components{Name: "redhat-best-practices-for-k8s/certsuite", Type: "container", Data: dci.Data{Version: "unstable"}},
+			expected:   CertsuiteComponent{Product: "certsuite", Version: "unstable"},
+			expectedOK: true,
+		},
+		{
+			name:       "Product without a build",
+			component:  dci.Components{Name: "Certsuite"},
+			expected:   CertsuiteComponent{Product: "certsuite"},
+			expectedOK: true,
+		},
+		{
+			name:       "Image reference with a tag",
+			component:  dci.Components{Name: "quay.io/redhat-best-practices-for-k8s/certsuite:v5.2.1"},
+			expected:   CertsuiteComponent{Product: "certsuite", Version: "v5.2.1", IsRelease: true},
+			expectedOK: true,
+		},
+		{
+			name:       "Image reference with a registry port and a digest",
+			component:  dci.Components{Name: "registry.example.com:5000/certsuite@sha256:0a1b2c3d", Version: "v5.2.1"},
+			expected:   CertsuiteComponent{Product: "certsuite", Version: "v5.2.1", IsRelease: true},
+			expectedOK: true,
+		},
+		{
+			name:       "Image reference with a tag and a digest",
+			component:  dci.Components{Name: "quay.io/testnetworkfunction/cnf-certification-test:0a1b2c3@sha256:4e5f6a7b"},
+			expected:   CertsuiteComponent{Product: "cnf-certification-test", Commit: "0a1b2c3"},
+			expectedOK: true,
+		},
+		{
+			name:       "Version suffix",
+			component:  dci.Components{Name: "certsuite-v5.2.1"},
+			expected:   CertsuiteComponent{Product: "certsuite", Version: "v5.2.1", IsRelease: true},
+			expectedOK: true,
+		},
+		{
+			name:      "Unrelated component mentioning certsuite",
+			component: dci.Components{Name: "certsuite-probe 1.0.0"},
+		},
+		{
+			name:      "OpenShift",
+			component: dci.Components{Name: "ocp 4.16.3", Type: "ocp"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parsed, ok := parseCertsuiteComponent(tc.component)
+
+			assert.Equal(t, tc.expectedOK, ok)
+			if !tc.expectedOK {
+				return
+			}
+			assert.Equal(t, tc.expected, parsed)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package pkg
+
+import (
+	"fmt"
+	"log"
+
+	dci "github.com/sebrandon1/go-dci/lib"
+	"github.com/sirupsen/logrus"
+)
+
+// DciJobComponent is a component, such as the OCP build, the CNI plugin or a partner
+// operator, that was part of a certsuite DCI job.
+type DciJobComponent struct {
+	ComponentID string
+	Type        string
+	Name        string
+	Version     string
+}
+
+// certsuiteJobComponents returns every component of the certsuite DCI jobs created in
+// the window, by job ID. Components repeated within a job are kept once.
+func certsuiteJobComponents(runs []dci.JobsResponse, window Window) map[string][]DciJobComponent {
+	components := map[string][]DciJobComponent{}
+	forEachCertsuiteJob(runs, window, func(job dci.Job, _ CertsuiteComponent) {
+		seen := map[[2]string]bool{}
+		jobComponents := []DciJobComponent{}
+		for _, c := range job.Components {
+			key := [2]string{c.Type, c.Name}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			version := c.Version
+			if version == "" {
+				version = c.Data.Version
+			}
+			jobComponents = append(jobComponents, DciJobComponent{ComponentID: c.ID, Type: c.Type, Name: c.Name, Version: version})
+		}
+		components[job.ID] = jobComponents
+	})
+	return components
+}
+
+// storeDciJobComponents replaces the stored components of every job.
+func storeDciJobComponents(store Store, components map[string][]DciJobComponent) (int, error) {
+	stored := 0
+	for jobID, jobComponents := range components {
+		if err := store.ReplaceDciJobComponents(jobID, jobComponents); err != nil {
+			return stored, fmt.Errorf("failed to store components of DCI job %s: %w", jobID, err)
+		}
+		stored += len(jobComponents)
+	}
+	log.Printf("Stored %d components of %d DCI jobs", stored, len(components))
+	return stored, nil
+}
+
+func (s *sqlStore) ReplaceDciJobComponents(jobID string, components []DciJobComponent) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	rollback := func(err error) error {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logrus.Errorf("failed to roll back components of DCI job %s: %v", jobID, rbErr)
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM dci_job_components WHERE job_id = ?;`), jobID); err != nil {
+		return rollback(err)
+	}
+	insert := s.dialect.rebind(`INSERT INTO dci_job_components (job_id, type, name, component_id, version)
+		VALUES (?, ?, ?, ?, ?);`)
+	for _, c := range components {
+		if _, err := tx.Exec(insert, jobID, c.Type, c.Name, c.ComponentID, c.Version); err != nil {
+			return rollback(err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetDciJobComponents returns the stored components of a DCI job, ordered by type and name.
+func (s *sqlStore) GetDciJobComponents(jobID string) ([]DciJobComponent, error) {
+	rows, err := s.db.Query(s.dialect.rebind(`
+		SELECT component_id, type, name, version FROM dci_job_components
+		WHERE job_id = ? ORDER BY type, name;`), jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dci_job_components: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close dci_job_components rows: %v", err)
+		}
+	}()
+
+	var components []DciJobComponent
+	for rows.Next() {
+		var c DciJobComponent
+		if err := rows.Scan(&c.ComponentID, &c.Type, &c.Name, &c.Version); err != nil {
+			return nil, fmt.Errorf("failed to scan dci_job_components row: %w", err)
+		}
+		components = append(components, c)
+	}
+	return components, rows.Err()
+}
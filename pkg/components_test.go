@@ -0,0 +1,49 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreDciJobComponents(t *testing.T) {
+	runs := dciRunsFromJSON(t, `{"jobs": [
+		{
+			"id": "job-1",
+			"created_at": "2024-11-26T12:00:00.000000",
+			"components": [
+				{"id": "c-1", "type": "ocp", "name": "OpenShift 4.16.3", "version": "4.16.3"},
+				{"id": "c-2", "type": "certsuite", "name": "certsuite v5.2.1", "version": "v5.2.1"},
+				{"id": "c-3", "type": "cni", "name": "ovn-kubernetes", "data": {"version": "24.03"}},
+				{"id": "c-3", "type": "cni", "name": "ovn-kubernetes", "data": {"version": "24.03"}}
+			]
+		},
+		{
+			"id": "job-2",
+			"created_at": "2024-11-26T12:00:00.000000",
+			"components": [{"id": "c-1", "type": "ocp", "name": "OpenShift 4.16.3"}]
+		}
+	]}`)
+
+	components := certsuiteJobComponents(runs, novemberWindow)
+
+	// job-2 did not run certsuite
+	require.Len(t, components, 1)
+	expected := []DciJobComponent{
+		{ComponentID: "c-2", Type: "certsuite", Name: "certsuite v5.2.1", Version: "v5.2.1"},
+		{ComponentID: "c-3", Type: "cni", Name: "ovn-kubernetes", Version: "24.03"},
+		{ComponentID: "c-1", Type: "ocp", Name: "OpenShift 4.16.3", Version: "4.16.3"},
+	}
+
+	// Re-running the sync replaces the job's components instead of duplicating them
+	store := newSQLiteStore(t)
+	for run := 0; run < 2; run++ {
+		written, err := storeDciJobComponents(store, components)
+		require.NoError(t, err)
+		assert.Equal(t, len(expected), written)
+	}
+	stored, err := store.GetDciJobComponents("job-1")
+	require.NoError(t, err)
+	assert.Equal(t, expected, stored)
+}
@@ -2,67 +2,279 @@ package pkg
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"time"
 
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/config"
 	"github.com/sirupsen/logrus"
 )
 
-// insertComponentData inserts component details into the dci_components table.
-func insertComponentData(db *sql.DB, jobID, commit, createdAt string, totalSuccess, totalFailures, totalErrors, totalSkips int) error {
-	if jobID == "" || commit == "" {
-		return fmt.Errorf("invalid input: jobID and commit_hash cannot be empty")
-	}
-	if totalSuccess < 0 || totalFailures < 0 || totalErrors < 0 || totalSkips < 0 {
-		return fmt.Errorf("invalid input: totalSuccess=%v, totalFailures=%v, totalErrors=%v, totalSkips=%v", totalSuccess, totalFailures, totalErrors, totalSkips)
-	}
-
-	insertQuery := `
-        INSERT INTO dci_components (job_id, commit_hash, createdAt, totalSuccess, totalFailures, totalErrors, totalSkips)
-        VALUES (?, ?, ?, ?, ?, ?, ?)
-        ON DUPLICATE KEY UPDATE 
-        commit_hash = VALUES(commit_hash),
-        createdAt = VALUES(createdAt),
-        totalSuccess = totalSuccess + VALUES(totalSuccess),
-        totalFailures = totalFailures + VALUES(totalFailures),
-        totalErrors = totalErrors + VALUES(totalErrors),
-        totalSkips = totalSkips + VALUES(totalSkips);
-    `
-	_, err := db.Exec(insertQuery, jobID, commit, createdAt, totalSuccess, totalFailures, totalErrors, totalSkips)
+// insertComponentData inserts the certsuite results of a DCI job into the dci_components table.
+func insertComponentData(db *sql.DB, d dialect, job DciJob) error {
+	if job.JobID == "" {
+		return fmt.Errorf("invalid input: jobID cannot be empty")
+	}
+	if job.TotalSuccess < 0 || job.TotalFailures < 0 || job.TotalErrors < 0 || job.TotalSkips < 0 {
+		return fmt.Errorf("invalid input: totalSuccess=%v, totalFailures=%v, totalErrors=%v, totalSkips=%v",
+			job.TotalSuccess, job.TotalFailures, job.TotalErrors, job.TotalSkips)
+	}
+
+	fields := []struct {
+		column string
+		value  any
+	}{
+		{"job_id", job.JobID},
+		{"commit_hash", job.CommitHash},
+		{"createdAt", job.CreatedAt},
+		{"totalSuccess", job.TotalSuccess},
+		{"totalFailures", job.TotalFailures},
+		{"totalErrors", job.TotalErrors},
+		{"totalSkips", job.TotalSkips},
+		{"ocp_version", job.OcpVersion},
+		{"topic", job.Topic},
+		{"remoteci_id", job.RemoteciID},
+		{"team_id", job.TeamID},
+		{"product", job.Product},
+		{"certsuite_version", job.CertsuiteVersion},
+		{"is_release", job.IsRelease},
+		{"state", job.State},
+		{"status", job.Status},
+		{"status_reason", job.StatusReason},
+		{"started_at", nullableTime(job.StartedAt)},
+		{"ended_at", nullableTime(job.EndedAt)},
+		{"duration", job.Duration},
+		{"outcome", job.Outcome},
+	}
+	columns := make([]string, 0, len(fields))
+	values := make([]any, 0, len(fields))
+	var assignments []string
+	for _, f := range fields {
+		columns = append(columns, f.column)
+		values = append(values, f.value)
+		if f.column != "job_id" {
+			assignments = append(assignments, f.column+" = "+d.excluded(f.column))
+		}
+	}
+
+	// A job is re-read by every overlapping sync, so its row is replaced rather than added to
+	insertQuery := d.upsertQuery("dci_components", columns, []string{"job_id"}, assignments...)
+	_, err := db.Exec(insertQuery, values...)
 	return err
 }
 
 // insertQuayData inserts a record of Quay image pulls into the aggregated_logs table.
-func insertQuayData(db *sql.DB, datetime string, count int, kind string) error {
+func insertQuayData(db *sql.DB, d dialect, aggregate QuayAggregate) error {
+	datetime, count, kind := aggregate.Datetime, aggregate.Count, aggregate.Kind
 	log.Printf("Received datetime: %v, count: %v, kind: %v", datetime, count, kind)
 
 	if datetime == "" || kind == "" || count < 0 {
 		return fmt.Errorf("invalid input: datetime=%v, kind=%v, count=%d (datetime/kind cannot be empty, count cannot be negative)", datetime, kind, count)
 
 	}
+	if aggregate.Namespace == "" || aggregate.Repository == "" {
+		return fmt.Errorf("invalid input: namespace and repository cannot be empty")
+	}
 
-	parsedDate, err := time.Parse("Mon, 02 Jan 2006 15:04:05 -0700", datetime)
-	if err != nil {
+	if _, err := time.Parse("2006-01-02", datetime); err != nil {
 		return fmt.Errorf("invalid datetime format: %v, expected YYYY-MM-DD", datetime)
 	}
-	dateStr := parsedDate.Format("2006-01-02")
 
-	// Define the insert query with ON DUPLICATE KEY UPDATE
-	insertQuery := `
-    INSERT INTO aggregated_logs (datetime, count, kind)
-	VALUES (?, ?, ?)
-	ON DUPLICATE KEY UPDATE count = count + VALUES(count);`
+	// Quay reports the full count of each day, so a re-run replaces the row instead of adding to it
+	insertQuery := d.upsertQuery("aggregated_logs",
+		[]string{"datetime", "namespace", "repository", "count", "kind"},
+		[]string{"datetime", "namespace", "repository", "kind"},
+		"count = "+d.excluded("count"),
+	)
 
-	log.Printf("🚀 Inserting into DB: datetime=%s, count=%d, kind=%s", dateStr, count, kind)
-	_, err = db.Exec(insertQuery, dateStr, count, kind)
+	log.Printf("🚀 Inserting into DB: datetime=%s, repository=%s/%s, count=%d, kind=%s", datetime, aggregate.Namespace, aggregate.Repository, count, kind)
+	_, err := db.Exec(insertQuery, datetime, aggregate.Namespace, aggregate.Repository, count, kind)
 	if err != nil {
 		log.Printf("Error executing insert query: %v", err)
 	}
 	return err
 }
 
+// dbTimestampFormat is the layout of the timestamps passed as query arguments,
+// which every backend accepts for TIMESTAMP columns.
+const dbTimestampFormat = "2006-01-02 15:04:05"
+
+// dbTimeLayouts are the text layouts in which drivers may return DATE and TIMESTAMP columns.
+var dbTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999",
+	"2006-01-02T15:04:05.999999",
+	time.RFC3339Nano,
+	"2006-01-02",
+}
+
+// dbTime scans DATE and TIMESTAMP columns, which drivers return either as
+// time.Time or as text depending on the backend, into a time.Time.
+type dbTime struct {
+	time.Time
+}
+
+func (t *dbTime) Scan(src any) error {
+	var text string
+	switch v := src.(type) {
+	case nil:
+		// NULL columns scan to the zero time
+		t.Time = time.Time{}
+		return nil
+	case time.Time:
+		t.Time = v
+		return nil
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	default:
+		return fmt.Errorf("unsupported time value %v of type %T", src, src)
+	}
+	for _, layout := range dbTimeLayouts {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported time format: %s", text)
+}
+
+// dciTime formats t in the layout of the DCI API, or returns an empty string for the zero time.
+func (t dbTime) dciTime() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(dciTimeFormat)
+}
+
+// nullableTime returns a DCI timestamp as a query argument, NULL if it is empty.
+func nullableTime(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// getQuayData reads every row of the aggregated_logs table.
+func getQuayData(db *sql.DB) ([]QuayAggregate, error) {
+	rows, err := db.Query(`SELECT datetime, namespace, repository, count, kind FROM aggregated_logs ORDER BY datetime, namespace, repository, kind;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aggregated_logs: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close aggregated_logs rows: %v", err)
+		}
+	}()
+
+	var aggregates []QuayAggregate
+	for rows.Next() {
+		var a QuayAggregate
+		var datetime dbTime
+		if err := rows.Scan(&datetime, &a.Namespace, &a.Repository, &a.Count, &a.Kind); err != nil {
+			return nil, fmt.Errorf("failed to scan aggregated_logs row: %w", err)
+		}
+		a.Datetime = datetime.Format("2006-01-02")
+		aggregates = append(aggregates, a)
+	}
+	return aggregates, rows.Err()
+}
+
+// getComponentData reads every row of the dci_components table.
+func getComponentData(db *sql.DB) ([]DciJob, error) {
+	rows, err := db.Query(`
+        SELECT job_id, commit_hash, createdAt, totalSuccess, totalFailures, totalErrors, totalSkips, ocp_version,
+            topic, remoteci_id, team_id, product, certsuite_version, is_release,
+            state, status, status_reason, started_at, ended_at, duration, outcome
+        FROM dci_components ORDER BY createdAt, job_id;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dci_components: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close dci_components rows: %v", err)
+		}
+	}()
+
+	var jobs []DciJob
+	for rows.Next() {
+		var j DciJob
+		var createdAt, startedAt, endedAt dbTime
+		if err := rows.Scan(&j.JobID, &j.CommitHash, &createdAt, &j.TotalSuccess, &j.TotalFailures, &j.TotalErrors, &j.TotalSkips, &j.OcpVersion,
+			&j.Topic, &j.RemoteciID, &j.TeamID, &j.Product, &j.CertsuiteVersion, &j.IsRelease,
+			&j.State, &j.Status, &j.StatusReason, &startedAt, &endedAt, &j.Duration, &j.Outcome); err != nil {
+			return nil, fmt.Errorf("failed to scan dci_components row: %w", err)
+		}
+		j.CreatedAt = createdAt.Format(dciTimeFormat)
+		j.StartedAt, j.EndedAt = startedAt.dciTime(), endedAt.dciTime()
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// sqlStore is the database/sql implementation of Store shared by every backend.
+type sqlStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// exists reports whether query, which selects a constant, returns a row.
+func (s *sqlStore) exists(query string, args ...any) (bool, error) {
+	var found int
+	err := s.db.QueryRow(s.dialect.rebind(query), args...).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *sqlStore) UpsertQuayAggregate(aggregate QuayAggregate) (bool, error) {
+	existed, err := s.exists(`SELECT 1 FROM aggregated_logs WHERE datetime = ? AND namespace = ? AND repository = ? AND kind = ?;`,
+		aggregate.Datetime, aggregate.Namespace, aggregate.Repository, aggregate.Kind)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up aggregated_logs row: %w", err)
+	}
+	if err := insertQuayData(s.db, s.dialect, aggregate); err != nil {
+		return false, err
+	}
+	return !existed, nil
+}
+
+func (s *sqlStore) AssignLegacyQuayAggregates(repository QuayRepository) (int, error) {
+	res, err := s.db.Exec(s.dialect.rebind(`UPDATE aggregated_logs SET namespace = ?, repository = ?
+		WHERE namespace = '' AND repository = '';`), repository.Namespace, repository.Name)
+	if err != nil {
+		return 0, err
+	}
+	assigned, err := res.RowsAffected()
+	return int(assigned), err
+}
+
+func (s *sqlStore) UpsertDciJob(job DciJob) (bool, error) {
+	existed, err := s.exists(`SELECT 1 FROM dci_components WHERE job_id = ?;`, job.JobID)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up dci_components row: %w", err)
+	}
+	if err := insertComponentData(s.db, s.dialect, job); err != nil {
+		return false, err
+	}
+	return !existed, nil
+}
+
+func (s *sqlStore) GetQuayAggregates() ([]QuayAggregate, error) {
+	return getQuayData(s.db)
+}
+
+func (s *sqlStore) GetDciJobs() ([]DciJob, error) {
+	return getComponentData(s.db)
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
 // pingDB verifies the database connection.
 func pingDB(db *sql.DB) error {
 	logrus.Info("Pinging the database to verify connection...")
@@ -86,58 +298,62 @@ func createDatabase(db *sql.DB, dbName string) error {
 	return nil
 }
 
-// createTables creates the required tables if they do not exist.
-func createTables(db *sql.DB) error {
-	logrus.Info("Creating tables if they do not exist...")
-
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS aggregated_logs (
-			datetime DATE NOT NULL,
-			count INT UNSIGNED NOT NULL DEFAULT 0,  
-			kind VARCHAR(255) NOT NULL,  
-			PRIMARY KEY (datetime, kind)
-		);`,
-
-		`CREATE TABLE IF NOT EXISTS dci_components (
-			job_id VARCHAR(36) PRIMARY KEY,       
-			commit_hash VARCHAR(255) NOT NULL,  
-			createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, 
-			totalSuccess INT DEFAULT 0,       
-			totalFailures INT DEFAULT 0,      
-			totalErrors INT DEFAULT 0,        
-			totalSkips INT DEFAULT 0          
-		);`,
-	}
-
-	for _, query := range queries {
-		if _, err := db.Exec(query); err != nil {
-			return fmt.Errorf("failed to create table: %w", err)
-		}
-	}
-
-	logrus.Info("Tables created successfully.")
-	return nil
-}
-
-// chooseDatabase initializes and returns a database connection based on the DB_CHOICE environment variable
-func ChooseDatabase() (*sql.DB, error) {
-	dbChoice := os.Getenv("DB_CHOICE") // Expecting "local" or "aws"
+// ChooseDatabase initializes and returns a Store based on the DB_CHOICE environment variable
+func ChooseDatabase() (Store, error) {
+	dbChoice := config.AppConfig.DBChoice // Expecting "local", "aws", "sqlite" or "postgres"
 	var db *sql.DB
 	var err error
 
-	if dbChoice == "aws" {
+	switch dbChoice {
+	case "aws":
 		db, err = initDBAWS()
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize AWS database: %w", err)
 		}
-	} else {
+	case "sqlite":
+		db, err = initDBSQLite(config.AppConfig.DBPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize SQLite database: %w", err)
+		}
+		return &sqlStore{db: db, dialect: sqliteDialect}, nil
+	case "postgres":
+		db, err = initDBPostgres()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize PostgreSQL database: %w", err)
+		}
+		return &sqlStore{db: db, dialect: postgresDialect}, nil
+	default:
 		db, err = initDBLocal()
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize local database: %w", err)
 		}
 	}
 
-	return db, nil
+	return &sqlStore{db: db, dialect: mysqlDialect}, nil
+}
+
+// connectDatabase opens the database selected by DB_CHOICE without touching its schema.
+func connectDatabase() (*sql.DB, dialect, error) {
+	var db *sql.DB
+	var err error
+	d := mysqlDialect
+
+	switch config.AppConfig.DBChoice {
+	case "aws":
+		db, err = connectDBAWS()
+	case "sqlite":
+		db, err = connectDBSQLite(config.AppConfig.DBPath)
+		d = sqliteDialect
+	case "postgres":
+		db, err = ConnectToPostgresDB()
+		d = postgresDialect
+	default:
+		db, err = ConnectToLocalDB()
+	}
+	if err != nil {
+		return nil, d, err
+	}
+	return db, d, nil
 }
 
 func ConnectToAWSDB() (*sql.DB, string, error) {
@@ -173,7 +389,8 @@ func ConnectToAWSDB() (*sql.DB, string, error) {
 	return db, newDBName, nil
 }
 
-func initDBAWS() (*sql.DB, error) {
+// connectDBAWS connects to the certsuite database on the AWS MySQL server, creating it if needed.
+func connectDBAWS() (*sql.DB, error) {
 	// Connect to AWS MySQL
 	db, newDBName, err := ConnectToAWSDB()
 	if err != nil {
@@ -210,11 +427,18 @@ func initDBAWS() (*sql.DB, error) {
 
 	// Log a successful reconnection and ping with the new database.
 	logrus.Infof("Successfully connected to database '%s' and pinged the database.", newDBName)
+	return db, nil
+}
+
+func initDBAWS() (*sql.DB, error) {
+	db, err := connectDBAWS()
+	if err != nil {
+		return nil, err
+	}
 
-	// Create tables in the new database.
-	logrus.Info("Creating tables in the database...")
-	if err := createTables(db); err != nil {
-		return nil, fmt.Errorf("failed to create tables in database %s: %w", newDBName, err)
+	// Bring the schema up to date, refusing databases migrated by a newer binary.
+	if err := prepareSchema(db, mysqlDialect); err != nil {
+		return nil, fmt.Errorf("failed to prepare schema of AWS MySQL database: %w", err)
 	}
 
 	logrus.Info("AWS MySQL database initialized successfully.")
@@ -231,19 +455,19 @@ func ConnectToLocalDB() (*sql.DB, error) {
 	logrus.Info("Opening connection to local MySQL server...")
 
 	// Initial connection (to check database existence)
-	db, err := sql.Open("mysql", rootDSN)
+	rootDB, err := sql.Open("mysql", rootDSN)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to MySQL server: %w", err)
 	}
 	defer func() {
-		if err := db.Close(); err != nil {
+		if err := rootDB.Close(); err != nil {
 			logrus.Errorf("failed to close MySQL connection: %v", err)
 		}
 	}()
 
 	// Check if the database exists
 	var exists int
-	err = db.QueryRow("SELECT COUNT(*) FROM INFORMATION_SCHEMA.SCHEMATA WHERE SCHEMA_NAME = ?", dbName).Scan(&exists)
+	err = rootDB.QueryRow("SELECT COUNT(*) FROM INFORMATION_SCHEMA.SCHEMATA WHERE SCHEMA_NAME = ?", dbName).Scan(&exists)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check if database exists: %w", err)
 	}
@@ -251,7 +475,7 @@ func ConnectToLocalDB() (*sql.DB, error) {
 	// Create database if it does not exist
 	if exists == 0 {
 		logrus.Infof("Database '%s' does not exist, creating...", dbName)
-		if _, err := db.Exec("CREATE DATABASE " + dbName); err != nil {
+		if _, err := rootDB.Exec("CREATE DATABASE " + dbName); err != nil {
 			return nil, fmt.Errorf("failed to create database: %w", err)
 		}
 		logrus.Infof("Database '%s' created successfully.", dbName)
@@ -260,7 +484,7 @@ func ConnectToLocalDB() (*sql.DB, error) {
 	}
 
 	// Connect to the newly ensured database
-	db, err = sql.Open("mysql", finalDSN)
+	db, err := sql.Open("mysql", finalDSN)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to MySQL database '%s': %w", dbName, err)
 	}
@@ -283,9 +507,9 @@ func initDBLocal() (*sql.DB, error) {
 		return nil, err
 	}
 
-	// Create tables in the database
-	if err := createTables(db); err != nil {
-		return nil, fmt.Errorf("failed to create tables in MySQL database: %w", err)
+	// Bring the schema up to date, refusing databases migrated by a newer binary.
+	if err := prepareSchema(db, mysqlDialect); err != nil {
+		return nil, fmt.Errorf("failed to prepare schema of MySQL database: %w", err)
 	}
 
 	logrus.Info("Local MySQL database initialized successfully.")
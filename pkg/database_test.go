@@ -11,64 +11,84 @@ import (
 func TestInsertComponentData(t *testing.T) {
 	tests := []struct {
 		name            string
-		jobID           string
-		commit          string
-		createdAt       string
-		totalSuccess    int
-		totalFailures   int
-		totalErrors     int
-		totalSkips      int
+		job             DciJob
 		mockQueryResult func(mock sqlmock.Sqlmock)
 		expectedError   bool
 	}{
 		{
-			name:          "Successful insertion",
-			jobID:         "job123",
-			commit:        "abc123",
-			createdAt:     "2024-11-26T12:00:00Z",
-			totalSuccess:  10,
-			totalFailures: 2,
-			totalErrors:   1,
-			totalSkips:    5,
+			name: "Successful insertion",
+			job: DciJob{
+				JobID:         "job123",
+				CommitHash:    "abc123",
+				CreatedAt:     "2024-11-26T12:00:00Z",
+				TotalSuccess:  10,
+				TotalFailures: 2,
+				TotalErrors:   1,
+				TotalSkips:    5,
+				OcpVersion:    "4.16.3",
+				Topic:         "OCP-4.16",
+				RemoteciID:    "remoteci-1",
+				TeamID:        "team-1",
+				Status:        "success",
+				StartedAt:     "2024-11-26T12:00:00Z",
+				EndedAt:       "2024-11-26T13:00:00Z",
+				Duration:      3600,
+				Outcome:       JobOutcomeTested,
+			},
 			mockQueryResult: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("INSERT OR REPLACE INTO dci_components").
-					WithArgs("job123", "abc123", "2024-11-26T12:00:00Z", 10, 2, 1, 5).
+				mock.ExpectExec("INSERT INTO dci_components").
+					WithArgs("job123", "abc123", "2024-11-26T12:00:00Z", 10, 2, 1, 5, "4.16.3", "OCP-4.16", "remoteci-1", "team-1", "", "", false,
+						"", "success", "", "2024-11-26T12:00:00Z", "2024-11-26T13:00:00Z", 3600, JobOutcomeTested).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			expectedError: false,
 		},
 		{
-			name:          "Database error",
-			jobID:         "job456",
-			commit:        "def456",
-			createdAt:     "2024-11-26T13:00:00Z",
-			totalSuccess:  5,
-			totalFailures: 1,
-			totalErrors:   0,
-			totalSkips:    2,
+			name: "Database error",
+			job: DciJob{
+				JobID:         "job456",
+				CommitHash:    "def456",
+				CreatedAt:     "2024-11-26T13:00:00Z",
+				TotalSuccess:  5,
+				TotalFailures: 1,
+				TotalErrors:   0,
+				TotalSkips:    2,
+			},
 			mockQueryResult: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("INSERT OR REPLACE INTO dci_components").
-					WithArgs("job456", "def456", "2024-11-26T13:00:00Z", 5, 1, 0, 2).
+				mock.ExpectExec("INSERT INTO dci_components").
+					WithArgs("job456", "def456", "2024-11-26T13:00:00Z", 5, 1, 0, 2, "", "", "", "", "", "", false,
+						"", "", "", nil, nil, 0, "").
 					WillReturnError(sql.ErrConnDone)
 			},
 			expectedError: true,
 		},
 		{
-			name:          "Empty commit hash",
-			jobID:         "job789",
-			commit:        "",
-			createdAt:     "2024-11-26T14:00:00Z",
-			totalSuccess:  3,
-			totalFailures: 0,
-			totalErrors:   0,
-			totalSkips:    1,
+			name: "Empty commit hash",
+			job: DciJob{
+				JobID:        "job789",
+				CommitHash:   "",
+				CreatedAt:    "2024-11-26T14:00:00Z",
+				TotalSuccess: 3,
+				TotalSkips:   1,
+			},
 			mockQueryResult: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("INSERT OR REPLACE INTO dci_components").
-					WithArgs("job789", "", "2024-11-26T14:00:00Z", 3, 0, 0, 1).
+				mock.ExpectExec("INSERT INTO dci_components").
+					WithArgs("job789", "", "2024-11-26T14:00:00Z", 3, 0, 0, 1, "", "", "", "", "", "", false,
+						"", "", "", nil, nil, 0, "").
 					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			expectedError: false,
 		},
+		{
+			name: "Empty job ID",
+			job: DciJob{
+				CommitHash:   "abc123",
+				CreatedAt:    "2024-11-26T14:00:00Z",
+				TotalSuccess: 3,
+			},
+			mockQueryResult: func(mock sqlmock.Sqlmock) {},
+			expectedError:   true,
+		},
 	}
 
 	for _, tc := range tests {
@@ -83,9 +103,10 @@ func TestInsertComponentData(t *testing.T) {
 
 			// Set up mock behavior
 			tc.mockQueryResult(mock)
+			mock.ExpectClose()
 
 			// Call the function
-			err = insertComponentData(db, tc.jobID, tc.commit, tc.createdAt, tc.totalSuccess, tc.totalFailures, tc.totalErrors, tc.totalSkips)
+			err = insertComponentData(db, mysqlDialect, tc.job)
 
 			// Validate the results
 			if tc.expectedError {
@@ -94,8 +115,8 @@ func TestInsertComponentData(t *testing.T) {
 				assert.NoError(t, err)
 			}
 
-			// Ensure all expectations were met
-			assert.NoError(t, mock.ExpectationsWereMet())
+			// Ensure all expectations were met once the deferred close has run
+			t.Cleanup(func() { assert.NoError(t, mock.ExpectationsWereMet()) })
 		})
 	}
 }
@@ -112,36 +133,32 @@ func TestInsertQuayData(t *testing.T) {
 	}{
 		{
 			name:     "Successful Insert",
-			datetime: "2024-11-26T12:00:00Z",
+			datetime: "2024-11-26",
 			count:    100,
 			kind:     "image_pulls",
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(`INSERT OR REPLACE INTO aggregated_logs \(datetime, count, kind\)`).
-					WithArgs("2024-11-26T12:00:00Z", 100, "image_pulls").
+				mock.ExpectExec(`INSERT INTO aggregated_logs \(datetime, namespace, repository, count, kind\)`).
+					WithArgs("2024-11-26", "redhat-best-practices-for-k8s", "certsuite", 100, "image_pulls").
 					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			expectedError: false,
 		},
 		{
-			name:     "Insert with Missing Kind",
-			datetime: "2024-11-26T12:00:00Z",
-			count:    50,
-			kind:     "",
-			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(`INSERT OR REPLACE INTO aggregated_logs \(datetime, count, kind\)`).
-					WithArgs("2024-11-26T12:00:00Z", 50, "").
-					WillReturnResult(sqlmock.NewResult(1, 1))
-			},
-			expectedError: false,
+			name:          "Insert with Missing Kind",
+			datetime:      "2024-11-26",
+			count:         50,
+			kind:          "",
+			mockSetup:     func(mock sqlmock.Sqlmock) {},
+			expectedError: true,
 		},
 		{
 			name:     "Database Error",
-			datetime: "2024-11-26T12:00:00Z",
+			datetime: "2024-11-26",
 			count:    200,
 			kind:     "image_pulls",
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(`INSERT OR REPLACE INTO aggregated_logs \(datetime, count, kind\)`).
-					WithArgs("2024-11-26T12:00:00Z", 200, "image_pulls").
+				mock.ExpectExec(`INSERT INTO aggregated_logs \(datetime, namespace, repository, count, kind\)`).
+					WithArgs("2024-11-26", "redhat-best-practices-for-k8s", "certsuite", 200, "image_pulls").
 					WillReturnError(sql.ErrConnDone)
 			},
 			expectedError: true,
@@ -161,9 +178,16 @@ func TestInsertQuayData(t *testing.T) {
 
 			// Apply the test-specific mock setup
 			tc.mockSetup(mock)
+			mock.ExpectClose()
 
 			// Call the function
-			err = insertQuayData(db, tc.datetime, tc.count, tc.kind)
+			err = insertQuayData(db, mysqlDialect, QuayAggregate{
+				Datetime:   tc.datetime,
+				Namespace:  certsuiteRepository.Namespace,
+				Repository: certsuiteRepository.Name,
+				Count:      tc.count,
+				Kind:       tc.kind,
+			})
 
 			// Validate the results
 			if tc.expectedError {
@@ -172,8 +196,8 @@ func TestInsertQuayData(t *testing.T) {
 				assert.NoError(t, err)
 			}
 
-			// Ensure all expectations were met
-			assert.NoError(t, mock.ExpectationsWereMet())
+			// Ensure all expectations were met once the deferred close has run
+			t.Cleanup(func() { assert.NoError(t, mock.ExpectationsWereMet()) })
 		})
 	}
 }
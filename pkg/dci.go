@@ -4,81 +4,303 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/redhat-best-practices-for-k8s/certsuite-overview/config"
 	dci "github.com/sebrandon1/go-dci/lib"
 )
 
-const (
-	daysBackLimit  = 7
-	certsuiteTests = "certsuite-tests_junit.xml"
-)
+// dciTimeFormat is the layout of the timestamps returned by the DCI API.
+const dciTimeFormat = "2006-01-02T15:04:05.999999"
+
+// dciOptions configures how DCI jobs are ingested.
+type dciOptions struct {
+	anon anonymizer
+	// certsuiteFiles select the result files holding certsuite's results, which are
+	// summed into dci_components and broken down per test case.
+	certsuiteFiles globs
+	// trackedFiles select the result files stored per file in dci_job_results.
+	trackedFiles globs
+}
 
-func FetchDciData() error {
-	var totalErrors, totalFailures, totalSkips, totalSuccess int
+// newDciOptions returns the ingestion options of the configuration.
+func newDciOptions(cfg config.Config) (dciOptions, error) {
+	opts := dciOptions{
+		anon:           newAnonymizer(cfg.AnonymizeSalt),
+		certsuiteFiles: cfg.CertsuiteResultFiles,
+		trackedFiles:   cfg.TrackedResultFiles,
+	}
+	if err := opts.certsuiteFiles.validate(); err != nil {
+		return opts, err
+	}
+	if err := opts.trackedFiles.validate(); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
 
-	// Initialize DCI client
+// FetchDciData fetches the certsuite runs created in the window from DCI and saves them in the store.
+func FetchDciData(store Store, window Window) (SyncResult, error) {
+	opts, err := newDciOptions(config.AppConfig)
+	if err != nil {
+		return SyncResult{}, err
+	}
 	dciClient := dci.NewClient(config.AppConfig.ClientID, config.AppConfig.APISecret)
+	runs, err := fetchDciRuns(dciClient, window)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	return storeDciData(store, dciClient, runs, window, opts)
+}
 
-	// Initialize database connection
-	db, err := ChooseDatabase()
+// storeDciData saves the certsuite jobs created in the window among the fetched runs,
+// with their teams, components, result files and test cases, and advances the DCI watermark.
+func storeDciData(store Store, dciClient *dci.Client, runs []dci.JobsResponse, window Window, opts dciOptions) (SyncResult, error) {
+	if err := checkAnonymization(store, opts.anon); err != nil {
+		return SyncResult{}, err
+	}
+	result, err := storeDciJobs(store, runs, window, opts)
 	if err != nil {
-		return err
+		return result, err
 	}
-	defer func() {
-		if closeErr := db.Close(); closeErr != nil {
-			log.Printf("Failed to close database connection: %v", closeErr)
-		}
-	}()
+	written, err := storeDciTeams(store, certsuiteTeams(runs, window, opts.anon))
+	result.Written += written
+	if err != nil {
+		return result, err
+	}
+	written, err = storeDciJobComponents(store, certsuiteJobComponents(runs, window))
+	result.Written += written
+	if err != nil {
+		return result, err
+	}
+	written, err = storeDciJobResults(store, certsuiteJobResults(runs, window, opts.trackedFiles))
+	result.Written += written
+	if err != nil {
+		return result, err
+	}
+	// Break the summed results down per test case from each job's JUnit report
+	written, err = storeDciTestCases(store, certsuiteJUnitFiles(runs, window, opts.certsuiteFiles), func(fileID string) ([]byte, error) {
+		return downloadDciFile(dciClient, fileID)
+	})
+	result.Written += written
+	if err != nil {
+		return result, err
+	}
+	if err := advanceWatermark(store, DciSource, result.Latest); err != nil {
+		return result, err
+	}
+	log.Println("Successfully fetched and stored DCI data.")
+	return result, nil
+}
 
-	log.Printf("Fetching DCI data for the last %d days", daysBackLimit)
+// fetchDciRuns pages through the DCI jobs back to the start of the window.
+func fetchDciRuns(dciClient *dci.Client, window Window) ([]dci.JobsResponse, error) {
+	// DCI can only page back from now, so fetch enough days to reach the window and filter later
+	daysBack := window.daysBack(time.Now())
+	log.Printf("Fetching DCI data for %s (%d days back)", window, daysBack)
 
 	// Fetch DCI runs
-	runs, err := dciClient.GetJobs(daysBackLimit)
+	runs, err := dciClient.GetJobs(daysBack)
 	if err != nil {
-		return fmt.Errorf("failed to fetch DCI runs: %w", err)
+		return nil, fmt.Errorf("failed to fetch DCI runs: %w", err)
 	}
 
 	log.Printf("Fetched %d DCI runs", len(runs))
+	return runs, nil
+}
 
-	// Store job and component data in the database
+// forEachCertsuiteJob calls fn for every DCI job created in the window that ran certsuite,
+// with its parsed certsuite component. A job is reported once even if several of its
+// components are certsuite.
+func forEachCertsuiteJob(runs []dci.JobsResponse, window Window, fn func(job dci.Job, certsuite CertsuiteComponent)) {
 	for _, run := range runs {
 		for _, job := range run.Jobs {
-			// Insert component information into the dci_components table
+			createdAt, err := time.Parse(dciTimeFormat, job.CreatedAt)
+			if err != nil {
+				log.Printf("Skipping DCI job %s with invalid creation date %q: %v", job.ID, job.CreatedAt, err)
+				continue
+			}
+			if !window.Contains(createdAt) {
+				continue
+			}
+
 			for _, component := range job.Components {
-				commitHash := "unknown"
-				if parts := strings.Split(component.Name, " "); len(parts) > 1 {
-					commitHash = parts[1]
-				}
-				if strings.Contains(component.Name, "cnf-certification-test") || strings.Contains(component.Name, "certsuite") {
-					totalErrors = 0
-					totalFailures = 0
-					totalSkips = 0
-					totalSuccess = 0
-					for _, result := range job.Results {
-						if result.Name == certsuiteTests {
-							totalErrors += result.Errors
-							totalFailures += result.Failures
-							totalSkips += result.Skips
-							totalSuccess += result.Success
-						}
-					}
-
-					log.Println("Inserting DCI component data into the database...")
-					log.Printf("Job ID: %s, Commit: %s, CreatedAt: %v, TotalSuccess: %d, TotalFailures: %d, TotalErrors: %d, TotalSkips: %d",
-						job.ID, commitHash, job.CreatedAt, totalSuccess, totalFailures, totalErrors, totalSkips)
-					log.Println("--------------------")
-
-					if err = insertComponentData(db, job.ID, commitHash, job.CreatedAt, totalSuccess, totalFailures, totalErrors, totalSkips); err != nil {
-						log.Printf(
-							"Error inserting DCI component entry: Job ID: %s, Commit: %s, CreatedAt: %v, TotalSuccess: %d, TotalFailures: %d, TotalErrors: %d, TotalSkips: %d. Error: %v",
-							job.ID, commitHash, job.CreatedAt, totalSuccess, totalFailures, totalErrors, totalSkips, err)
-						return fmt.Errorf("failed to insert DCI component data: %w", err)
-					}
+				if certsuite, ok := parseCertsuiteComponent(component); ok {
+					fn(job, certsuite)
+					break
 				}
 			}
 		}
 	}
-	log.Println("Successfully fetched and stored DCI data.")
-	return nil
+}
+
+// certsuiteJobs extracts the certsuite results of every DCI job created in the window that ran certsuite,
+// summing the result files selected by the options and anonymizing the team and remoteci identifiers.
+func certsuiteJobs(runs []dci.JobsResponse, window Window, opts dciOptions) []DciJob {
+	var jobs []DciJob
+	forEachCertsuiteJob(runs, window, func(job dci.Job, certsuite CertsuiteComponent) {
+		dciJob := DciJob{
+			JobID:            job.ID,
+			CommitHash:       certsuite.Commit,
+			CreatedAt:        job.CreatedAt,
+			OcpVersion:       ocpVersion(job),
+			Topic:            job.Topic.Name,
+			RemoteciID:       opts.anon.anonymize(job.RemoteciID),
+			TeamID:           opts.anon.anonymize(job.TeamID),
+			Product:          certsuite.Product,
+			CertsuiteVersion: certsuite.Version,
+			IsRelease:        certsuite.IsRelease,
+		}
+		matched := false
+		for _, result := range job.Results {
+			if opts.certsuiteFiles.match(result.Name) {
+				matched = true
+				dciJob.TotalErrors += result.Errors
+				dciJob.TotalFailures += result.Failures
+				dciJob.TotalSkips += result.Skips
+				dciJob.TotalSuccess += result.Success
+			}
+		}
+		if !matched {
+			log.Printf("DCI job %s ran certsuite but has no result file matching %v", job.ID, opts.certsuiteFiles)
+		}
+		dciJob.setJobStatus(job)
+		if dciJob.Outcome == JobOutcomeInfrastructureFailure {
+			log.Printf("DCI job %s finished with status %q (%s) without certsuite results, recording it as an infrastructure failure",
+				job.ID, job.Status, job.StatusReason)
+		}
+		jobs = append(jobs, dciJob)
+	})
+	return jobs
+}
+
+// ocpVersion returns the OpenShift version a DCI job ran on, from its "ocp" component,
+// a component named like "ocp 4.16.3", or failing both the minor version of a topic
+// named like "OCP-4.16". It returns an empty string if the job has none of them.
+func ocpVersion(job dci.Job) string {
+	for _, component := range job.Components {
+		if strings.EqualFold(component.Type, "ocp") && component.Version != "" {
+			return component.Version
+		}
+	}
+	for _, component := range job.Components {
+		fields := strings.Fields(component.Name)
+		if len(fields) > 1 && (strings.EqualFold(fields[0], "ocp") || strings.EqualFold(fields[0], "openshift")) {
+			return fields[1]
+		}
+	}
+	if prefix, version, ok := strings.Cut(job.Topic.Name, "-"); ok && strings.EqualFold(prefix, "ocp") {
+		return version
+	}
+	return ""
+}
+
+// storeDciJobs saves the certsuite results of every DCI job in the store.
+// DCI results are immutable once a job finishes, so each run replaces the job's row.
+// The result's Latest is the creation time of the newest job stored.
+func storeDciJobs(store Store, runs []dci.JobsResponse, window Window, opts dciOptions) (SyncResult, error) {
+	var result SyncResult
+	for _, job := range certsuiteJobs(runs, window, opts) {
+		log.Println("Inserting DCI component data into the database...")
+		log.Printf("Job ID: %s, Commit: %s, CreatedAt: %v, TotalSuccess: %d, TotalFailures: %d, TotalErrors: %d, TotalSkips: %d",
+			job.JobID, job.CommitHash, job.CreatedAt, job.TotalSuccess, job.TotalFailures, job.TotalErrors, job.TotalSkips)
+		log.Println("--------------------")
+
+		inserted, err := store.UpsertDciJob(job)
+		if err != nil {
+			log.Printf(
+				"Error inserting DCI component entry: Job ID: %s, Commit: %s, CreatedAt: %v, TotalSuccess: %d, TotalFailures: %d, TotalErrors: %d, TotalSkips: %d. Error: %v",
+				job.JobID, job.CommitHash, job.CreatedAt, job.TotalSuccess, job.TotalFailures, job.TotalErrors, job.TotalSkips, err)
+			return result, fmt.Errorf("failed to insert DCI component data: %w", err)
+		}
+		// certsuiteJobs only returns jobs whose creation date parses
+		createdAt, _ := time.Parse(dciTimeFormat, job.CreatedAt)
+		result.record(inserted, createdAt)
+	}
+	return result, nil
+}
+
+// RepairDciData recomputes the certsuite results of the DCI jobs created in the window
+// from DCI, and rewrites the stored rows that no longer match the source.
+// It returns the number of rows repaired.
+func RepairDciData(store Store, window Window) (int, error) {
+	opts, err := newDciOptions(config.AppConfig)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkAnonymization(store, opts.anon); err != nil {
+		return 0, err
+	}
+	runs, err := fetchDciRuns(dci.NewClient(config.AppConfig.ClientID, config.AppConfig.APISecret), window)
+	if err != nil {
+		return 0, err
+	}
+
+	stored, err := store.GetDciJobs()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read stored DCI jobs: %w", err)
+	}
+	return repairDciJobs(store, stored, certsuiteJobs(runs, window, opts))
+}
+
+// repairDciJobs rewrites the stored jobs that differ from the ones computed from DCI.
+func repairDciJobs(store Store, stored, computed []DciJob) (int, error) {
+	storedByID := make(map[string]DciJob, len(stored))
+	for _, job := range stored {
+		storedByID[job.JobID] = job
+	}
+
+	repaired := 0
+	for _, job := range computed {
+		current, ok := storedByID[job.JobID]
+		if !ok || sameDciResults(current, job) {
+			continue
+		}
+		log.Printf("Repairing DCI job %s: stored success=%d failures=%d errors=%d skips=%d, source success=%d failures=%d errors=%d skips=%d",
+			job.JobID, current.TotalSuccess, current.TotalFailures, current.TotalErrors, current.TotalSkips,
+			job.TotalSuccess, job.TotalFailures, job.TotalErrors, job.TotalSkips)
+		if _, err := store.UpsertDciJob(job); err != nil {
+			return repaired, fmt.Errorf("failed to repair DCI job %s: %w", job.JobID, err)
+		}
+		repaired++
+	}
+	return repaired, nil
+}
+
+// sameDciResults reports whether two rows of the same job hold the same data.
+func sameDciResults(a, b DciJob) bool {
+	return a.CommitHash == b.CommitHash &&
+		a.OcpVersion == b.OcpVersion &&
+		a.Topic == b.Topic &&
+		a.RemoteciID == b.RemoteciID &&
+		a.TeamID == b.TeamID &&
+		a.Product == b.Product &&
+		a.CertsuiteVersion == b.CertsuiteVersion &&
+		a.IsRelease == b.IsRelease &&
+		a.State == b.State &&
+		a.Status == b.Status &&
+		a.StatusReason == b.StatusReason &&
+		sameDciTime(a.StartedAt, b.StartedAt) &&
+		sameDciTime(a.EndedAt, b.EndedAt) &&
+		a.Duration == b.Duration &&
+		a.Outcome == b.Outcome &&
+		a.TotalSuccess == b.TotalSuccess &&
+		a.TotalFailures == b.TotalFailures &&
+		a.TotalErrors == b.TotalErrors &&
+		a.TotalSkips == b.TotalSkips
+}
+
+// sameDciTime reports whether two DCI timestamps are the same instant to the second,
+// the precision of MySQL TIMESTAMP columns. Stored timestamps also lose the trailing
+// zeros of their fractional seconds, so they are compared parsed rather than as text.
+func sameDciTime(a, b string) bool {
+	if a == b {
+		return true
+	}
+	at, aErr := time.Parse(dciTimeFormat, a)
+	bt, bErr := time.Parse(dciTimeFormat, b)
+	if aErr != nil || bErr != nil {
+		return false
+	}
+	return at.Truncate(time.Second).Equal(bt.Truncate(time.Second))
 }
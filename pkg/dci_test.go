@@ -0,0 +1,200 @@
+package pkg
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	dci "github.com/sebrandon1/go-dci/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dciRunsFromJSON decodes a DCI jobs API payload, as returned by GetJobs.
+func dciRunsFromJSON(t *testing.T, payload string) []dci.JobsResponse {
+	t.Helper()
+	var run dci.JobsResponse
+	if err := json.Unmarshal([]byte(payload), &run); err != nil {
+		t.Fatalf("failed to decode DCI payload: %v", err)
+	}
+	return []dci.JobsResponse{run}
+}
+
+// testDciOptions are the default ingestion options, without anonymization.
+var testDciOptions = dciOptions{
+	certsuiteFiles: globs{"certsuite-tests_junit.xml"},
+	trackedFiles:   globs{"*"},
+}
+
+// novemberWindow covers the DCI jobs of the test payloads.
+var novemberWindow = Window{
+	Since: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+	Until: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+}
+
+func TestStoreDciJobs(t *testing.T) {
+	runs := dciRunsFromJSON(t, `{"jobs": [
+		{
+			"id": "job-1",
+			"created_at": "2024-11-26T12:00:00.000000",
+			"topic": {"name": "OCP-4.16"},
+			"remoteci_id": "remoteci-1",
+			"team_id": "team-1",
+			"state": "active",
+			"status": "success",
+			"updated_at": "2024-11-26T13:30:00.000000",
+			"duration": 5400,
+			"components": [{"name": "ocp 4.16.3"}, {"name": "certsuite abc123"}],
+			"results": [
+				{"name": "certsuite-tests_junit.xml", "success": 10, "failures": 2, "errors": 1, "skips": 5},
+				{"name": "other_junit.xml", "success": 99}
+			]
+		},
+		{
+			"id": "job-2",
+			"created_at": "2024-11-27T12:00:00.000000",
+			"components": [{"name": "ocp 4.16.3"}],
+			"results": [{"name": "certsuite-tests_junit.xml", "success": 1}]
+		},
+		{
+			"id": "job-3",
+			"created_at": "2024-12-01T00:00:00.000000",
+			"components": [{"name": "certsuite def456"}],
+			"results": [{"name": "certsuite-tests_junit.xml", "success": 1}]
+		}
+	]}`)
+
+	store := &fakeStore{}
+	result, err := storeDciJobs(store, runs, novemberWindow, testDciOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, SyncResult{Inserted: 1, Latest: time.Date(2024, 11, 26, 12, 0, 0, 0, time.UTC)}, result)
+	assert.Equal(t, []DciJob{{
+		JobID:            "job-1",
+		CreatedAt:        "2024-11-26T12:00:00.000000",
+		TotalSuccess:     10,
+		TotalFailures:    2,
+		TotalErrors:      1,
+		TotalSkips:       5,
+		OcpVersion:       "4.16.3",
+		Topic:            "OCP-4.16",
+		RemoteciID:       "remoteci-1",
+		TeamID:           "team-1",
+		Product:          "certsuite",
+		CertsuiteVersion: "abc123",
+		State:            "active",
+		Status:           "success",
+		StartedAt:        "2024-11-26T12:00:00.000000",
+		EndedAt:          "2024-11-26T13:30:00.000000",
+		Duration:         5400,
+		Outcome:          JobOutcomeTested,
+	}}, store.dciJobs)
+}
+
+func TestOcpVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected string
+	}{
+		{
+			name:     "OCP component",
+			payload:  `{"topic": {"name": "OCP-4.16"}, "components": [{"name": "OpenShift 4.16.3", "type": "ocp", "version": "4.16.3"}]}`,
+			expected: "4.16.3",
+		},
+		{
+			name:     "Component named after OCP",
+			payload:  `{"components": [{"name": "certsuite v5.2.1"}, {"name": "ocp 4.15.12"}]}`,
+			expected: "4.15.12",
+		},
+		{
+			name:     "Topic only",
+			payload:  `{"topic": {"name": "OCP-4.17"}, "components": [{"name": "certsuite v5.2.1"}]}`,
+			expected: "4.17",
+		},
+		{
+			name:    "Unknown",
+			payload: `{"topic": {"name": "RHEL-9.4"}, "components": [{"name": "certsuite v5.2.1"}]}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var job dci.Job
+			if err := json.Unmarshal([]byte(tc.payload), &job); err != nil {
+				t.Fatalf("failed to decode DCI job: %v", err)
+			}
+			assert.Equal(t, tc.expected, ocpVersion(job))
+		})
+	}
+}
+
+func TestStoreDciJobsIsIdempotent(t *testing.T) {
+	runs := dciRunsFromJSON(t, `{"jobs": [{
+		"id": "job-1",
+		"created_at": "2024-11-26T12:00:00.000000",
+		"components": [{"name": "cnf-certification-test abc123"}, {"name": "certsuite abc123"}],
+		"results": [{"name": "certsuite-tests_junit.xml", "success": 10, "failures": 2}]
+	}]}`)
+	store := newSQLiteStore(t)
+
+	for run := 0; run < 3; run++ {
+		result, err := storeDciJobs(store, runs, novemberWindow, testDciOptions)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result.Inserted+result.Updated)
+	}
+
+	jobs, err := store.GetDciJobs()
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, 10, jobs[0].TotalSuccess)
+	assert.Equal(t, 2, jobs[0].TotalFailures)
+}
+
+func TestRepairDciJobs(t *testing.T) {
+	stored := []DciJob{
+		{JobID: "job-1", CommitHash: "abc123", TotalSuccess: 30, TotalFailures: 6},
+		{JobID: "job-2", CommitHash: "def456", TotalSuccess: 5},
+	}
+	computed := []DciJob{
+		{JobID: "job-1", CommitHash: "abc123", TotalSuccess: 10, TotalFailures: 2},
+		{JobID: "job-2", CommitHash: "def456", TotalSuccess: 5},
+		{JobID: "job-3", CommitHash: "0a1b2c3", TotalSuccess: 1},
+	}
+	store := &fakeStore{}
+
+	repaired, err := repairDciJobs(store, stored, computed)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, repaired)
+	assert.Equal(t, []DciJob{computed[0]}, store.dciJobs)
+}
+
+func TestRepairDciJobsKeepsStoredTimestamps(t *testing.T) {
+	job := DciJob{JobID: "job-1", CommitHash: "abc123", CreatedAt: "2024-11-26T12:00:00.123450",
+		Status: "success", StartedAt: "2024-11-26T12:00:00.123450", EndedAt: "2024-11-26T12:45:00.500000",
+		Duration: 2700, Outcome: JobOutcomeTested, TotalSuccess: 10}
+	store := newSQLiteStore(t)
+	_, err := store.UpsertDciJob(job)
+	require.NoError(t, err)
+
+	// The store drops the trailing zeros of the fractional seconds
+	stored, err := store.GetDciJobs()
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "2024-11-26T12:00:00.12345", stored[0].StartedAt)
+
+	repaired, err := repairDciJobs(store, stored, []DciJob{job})
+	require.NoError(t, err)
+	assert.Zero(t, repaired)
+
+	// MySQL keeps whole seconds only
+	stored[0].StartedAt, stored[0].EndedAt = "2024-11-26T12:00:00", "2024-11-26T12:45:00"
+	repaired, err = repairDciJobs(store, stored, []DciJob{job})
+	require.NoError(t, err)
+	assert.Zero(t, repaired)
+
+	job.EndedAt = "2024-11-26T12:46:00.500000"
+	repaired, err = repairDciJobs(store, stored, []DciJob{job})
+	require.NoError(t, err)
+	assert.Equal(t, 1, repaired)
+}
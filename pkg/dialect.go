@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dialect captures the SQL differences between the supported database backends.
+type dialect struct {
+	name string
+	// bindVar returns the placeholder for the n-th (1-based) query argument.
+	bindVar func(n int) string
+	// unsignedInt is the column type used for counters that cannot be negative.
+	unsignedInt string
+	// autoIncrementKey is the column definition of a generated integer primary key.
+	autoIncrementKey string
+	// excluded references the value proposed for insertion in an upsert.
+	excluded func(column string) string
+	// onConflict starts the upsert clause for a row whose keys already exist.
+	onConflict func(keys []string) string
+	// alterColumnType changes the type of a column, keeping its constraints. It returns no
+	// statement for SQLite, whose column types do not bound their values.
+	alterColumnType func(table, column, columnType, constraints string) []string
+}
+
+// questionMark is the placeholder style of MySQL and SQLite.
+func questionMark(int) string {
+	return "?"
+}
+
+var mysqlDialect = dialect{
+	name:             "mysql",
+	bindVar:          questionMark,
+	unsignedInt:      "INT UNSIGNED",
+	autoIncrementKey: "BIGINT AUTO_INCREMENT PRIMARY KEY",
+	excluded: func(column string) string {
+		return fmt.Sprintf("VALUES(%s)", column)
+	},
+	onConflict: func([]string) string {
+		return "ON DUPLICATE KEY UPDATE"
+	},
+	alterColumnType: func(table, column, columnType, constraints string) []string {
+		return []string{fmt.Sprintf("ALTER TABLE %s MODIFY %s %s %s;", table, column, columnType, constraints)}
+	},
+}
+
+var sqliteDialect = dialect{
+	name:             "sqlite",
+	bindVar:          questionMark,
+	unsignedInt:      "INT UNSIGNED",
+	autoIncrementKey: "INTEGER PRIMARY KEY AUTOINCREMENT",
+	excluded: func(column string) string {
+		return "excluded." + column
+	},
+	onConflict: func(keys []string) string {
+		return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET", strings.Join(keys, ", "))
+	},
+	alterColumnType: func(string, string, string, string) []string {
+		return nil
+	},
+}
+
+var postgresDialect = dialect{
+	name: "postgres",
+	bindVar: func(n int) string {
+		return fmt.Sprintf("$%d", n)
+	},
+	unsignedInt:      "INTEGER",
+	autoIncrementKey: "BIGSERIAL PRIMARY KEY",
+	excluded: func(column string) string {
+		return "excluded." + column
+	},
+	onConflict: func(keys []string) string {
+		return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET", strings.Join(keys, ", "))
+	},
+	alterColumnType: func(table, column, columnType, _ string) []string {
+		return []string{fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s;", table, column, columnType)}
+	},
+}
+
+// rebind rewrites the ? placeholders of query into the placeholder style of the dialect.
+func (d dialect) rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString(d.bindVar(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// upsertQuery builds an INSERT into table that applies the assignments
+// instead when a row with the same keys already exists.
+func (d dialect) upsertQuery(table string, columns, keys []string, assignments ...string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	return d.rebind(fmt.Sprintf("INSERT INTO %s (%s)\nVALUES (%s)\n%s %s;",
+		table, strings.Join(columns, ", "), placeholders, d.onConflict(keys), strings.Join(assignments, ",\n")))
+}
@@ -0,0 +1,55 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpsertQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  dialect
+		expected string
+	}{
+		{
+			name:    "MySQL",
+			dialect: mysqlDialect,
+			expected: "INSERT INTO aggregated_logs (datetime, count, kind)\n" +
+				"VALUES (?, ?, ?)\n" +
+				"ON DUPLICATE KEY UPDATE count = VALUES(count);",
+		},
+		{
+			name:    "SQLite",
+			dialect: sqliteDialect,
+			expected: "INSERT INTO aggregated_logs (datetime, count, kind)\n" +
+				"VALUES (?, ?, ?)\n" +
+				"ON CONFLICT (datetime, kind) DO UPDATE SET count = excluded.count;",
+		},
+		{
+			name:    "PostgreSQL",
+			dialect: postgresDialect,
+			expected: "INSERT INTO aggregated_logs (datetime, count, kind)\n" +
+				"VALUES ($1, $2, $3)\n" +
+				"ON CONFLICT (datetime, kind) DO UPDATE SET count = excluded.count;",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			query := tc.dialect.upsertQuery("aggregated_logs",
+				[]string{"datetime", "count", "kind"},
+				[]string{"datetime", "kind"},
+				"count = "+tc.dialect.excluded("count"))
+			assert.Equal(t, tc.expected, query)
+		})
+	}
+}
+
+func TestAlterColumnType(t *testing.T) {
+	assert.Equal(t, []string{"ALTER TABLE quay_pull_performers MODIFY name VARCHAR(128) NOT NULL DEFAULT '';"},
+		mysqlDialect.alterColumnType("quay_pull_performers", "name", "VARCHAR(128)", "NOT NULL DEFAULT ''"))
+	assert.Equal(t, []string{"ALTER TABLE quay_pull_performers ALTER COLUMN name TYPE VARCHAR(128);"},
+		postgresDialect.alterColumnType("quay_pull_performers", "name", "VARCHAR(128)", "NOT NULL DEFAULT ''"))
+	assert.Empty(t, sqliteDialect.alterColumnType("quay_pull_performers", "name", "VARCHAR(128)", "NOT NULL DEFAULT ''"))
+}
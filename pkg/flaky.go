@@ -0,0 +1,99 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FlakyTestGroup is a row of the flaky_tests view: a test case that both passed and
+// failed across the DCI jobs of one certsuite commit and OCP version.
+type FlakyTestGroup struct {
+	Name       string
+	CommitHash string
+	OcpVersion string
+	Passes     int
+	Failures   int
+}
+
+// FlakyTest is the flakiness of a test case across every commit and OCP version it flipped on.
+type FlakyTest struct {
+	Name string
+	// Score is 1 when the test passes and fails equally often on identical setups,
+	// and approaches 0 as one outcome dominates.
+	Score    float64
+	Passes   int
+	Failures int
+	// Configurations is the number of commit and OCP version pairs the test flipped on.
+	Configurations int
+}
+
+// RankFlakyTests scores every test case of the flaky groups, most flaky first.
+// Groups with fewer than minRuns passes and failures are too small to judge and are ignored.
+func RankFlakyTests(groups []FlakyTestGroup, minRuns int) []FlakyTest {
+	byName := map[string]*FlakyTest{}
+	flips := map[string]int{}
+	for _, g := range groups {
+		if g.Passes+g.Failures < minRuns {
+			continue
+		}
+		test, ok := byName[g.Name]
+		if !ok {
+			test = &FlakyTest{Name: g.Name}
+			byName[g.Name] = test
+		}
+		test.Passes += g.Passes
+		test.Failures += g.Failures
+		test.Configurations++
+		flips[g.Name] += 2 * min(g.Passes, g.Failures)
+	}
+
+	ranked := make([]FlakyTest, 0, len(byName))
+	for name, test := range byName {
+		test.Score = float64(flips[name]) / float64(test.Passes+test.Failures)
+		ranked = append(ranked, *test)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Passes+a.Failures != b.Passes+b.Failures {
+			return a.Passes+a.Failures > b.Passes+b.Failures
+		}
+		return a.Name < b.Name
+	})
+	return ranked
+}
+
+// AnalyzeFlakyTests ranks the flaky test cases recorded in the store.
+func AnalyzeFlakyTests(store Store, minRuns int) ([]FlakyTest, error) {
+	groups, err := store.GetFlakyTestGroups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read flaky tests: %w", err)
+	}
+	return RankFlakyTests(groups, minRuns), nil
+}
+
+func (s *sqlStore) GetFlakyTestGroups() ([]FlakyTestGroup, error) {
+	rows, err := s.db.Query(`SELECT name, commit_hash, ocp_version, passes, failures FROM flaky_tests ORDER BY name, commit_hash, ocp_version;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flaky_tests: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close flaky_tests rows: %v", err)
+		}
+	}()
+
+	var groups []FlakyTestGroup
+	for rows.Next() {
+		var g FlakyTestGroup
+		if err := rows.Scan(&g.Name, &g.CommitHash, &g.OcpVersion, &g.Passes, &g.Failures); err != nil {
+			return nil, fmt.Errorf("failed to scan flaky_tests row: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
@@ -0,0 +1,66 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankFlakyTests(t *testing.T) {
+	groups := []FlakyTestGroup{
+		{Name: "networking-icmpv4-connectivity", CommitHash: "abc123", OcpVersion: "4.16.3", Passes: 3, Failures: 3},
+		{Name: "networking-icmpv4-connectivity", CommitHash: "def456", OcpVersion: "4.16.3", Passes: 1, Failures: 1},
+		{Name: "lifecycle-pod-scheduling", CommitHash: "abc123", OcpVersion: "4.16.3", Passes: 9, Failures: 1},
+		{Name: "access-control-ssh-daemons", CommitHash: "abc123", OcpVersion: "4.15.0", Passes: 1, Failures: 1},
+	}
+
+	ranked := RankFlakyTests(groups, 3)
+
+	assert.Equal(t, []FlakyTest{
+		{Name: "networking-icmpv4-connectivity", Score: 1, Passes: 3, Failures: 3, Configurations: 1},
+		{Name: "lifecycle-pod-scheduling", Score: 0.2, Passes: 9, Failures: 1, Configurations: 1},
+	}, ranked)
+}
+
+func TestAnalyzeFlakyTests(t *testing.T) {
+	store := newSQLiteStore(t)
+	jobs := []DciJob{
+		{JobID: "job-1", CommitHash: "abc123", CreatedAt: "2024-11-01T00:00:00", OcpVersion: "4.16.3"},
+		{JobID: "job-2", CommitHash: "abc123", CreatedAt: "2024-11-02T00:00:00", OcpVersion: "4.16.3"},
+		// Same outcome flip, but on another OCP version
+		{JobID: "job-3", CommitHash: "abc123", CreatedAt: "2024-11-03T00:00:00", OcpVersion: "4.17.0"},
+		// Unrelated builds without a known commit or OCP version do not make a test flaky
+		{JobID: "job-4", CommitHash: "latest", CertsuiteVersion: "latest", CreatedAt: "2024-11-04T00:00:00", OcpVersion: "4.16.3"},
+		{JobID: "job-5", CommitHash: "latest", CertsuiteVersion: "latest", CreatedAt: "2024-11-05T00:00:00", OcpVersion: "4.16.3"},
+		{JobID: "job-6", CommitHash: "def456", CreatedAt: "2024-11-06T00:00:00"},
+		{JobID: "job-7", CommitHash: "def456", CreatedAt: "2024-11-07T00:00:00"},
+	}
+	for _, job := range jobs {
+		_, err := store.UpsertDciJob(job)
+		require.NoError(t, err)
+	}
+	require.NoError(t, store.ReplaceDciTestCases("job-1", []DciTestCase{
+		{Name: "networking-icmpv4-connectivity", Status: TestCasePassed},
+		{Name: "access-control-ssh-daemons", Status: TestCasePassed},
+	}))
+	require.NoError(t, store.ReplaceDciTestCases("job-2", []DciTestCase{
+		{Name: "networking-icmpv4-connectivity", Status: TestCaseFailed},
+		{Name: "access-control-ssh-daemons", Status: TestCaseSkipped},
+	}))
+	require.NoError(t, store.ReplaceDciTestCases("job-3", []DciTestCase{
+		{Name: "access-control-ssh-daemons", Status: TestCaseFailed},
+	}))
+
+	for _, jobs := range [][2]string{{"job-4", "job-5"}, {"job-6", "job-7"}} {
+		require.NoError(t, store.ReplaceDciTestCases(jobs[0], []DciTestCase{{Name: "platform-alteration-base-image", Status: TestCasePassed}}))
+		require.NoError(t, store.ReplaceDciTestCases(jobs[1], []DciTestCase{{Name: "platform-alteration-base-image", Status: TestCaseFailed}}))
+	}
+
+	ranked, err := AnalyzeFlakyTests(store, 2)
+
+	require.NoError(t, err)
+	assert.Equal(t, []FlakyTest{
+		{Name: "networking-icmpv4-connectivity", Score: 1, Passes: 1, Failures: 1, Configurations: 1},
+	}, ranked)
+}
@@ -0,0 +1,29 @@
+package pkg
+
+import (
+	"fmt"
+	"path"
+)
+
+// globs are glob patterns, in the syntax of path.Match, selecting names such as those of DCI result files or Quay tags.
+type globs []string
+
+// validate reports the first malformed pattern.
+func (g globs) validate() error {
+	for _, pattern := range g {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// match reports whether name matches any of the patterns.
+func (g globs) match(name string) bool {
+	for _, pattern := range g {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobs(t *testing.T) {
+	patterns := globs{"certsuite-*_junit.xml", "tests.xml"}
+	assert.True(t, patterns.match("certsuite-tests_junit.xml"))
+	assert.True(t, patterns.match("tests.xml"))
+	assert.False(t, patterns.match("other_junit.xml"))
+	assert.NoError(t, patterns.validate())
+	assert.Error(t, globs{"[junit"}.validate())
+}
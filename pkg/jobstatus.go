@@ -0,0 +1,118 @@
+package pkg
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	dci "github.com/sebrandon1/go-dci/lib"
+	"github.com/sirupsen/logrus"
+)
+
+// Outcomes of a certsuite DCI job.
+const (
+	// JobOutcomeTested is the outcome of a job that reported certsuite results.
+	JobOutcomeTested = "tested"
+	// JobOutcomeInfrastructureFailure is the outcome of a finished job that reported no
+	// certsuite results, typically because it failed before certsuite ran.
+	JobOutcomeInfrastructureFailure = "infrastructure_failure"
+	// JobOutcomeRunning is the outcome of a job that has not finished nor reported results yet.
+	JobOutcomeRunning = "running"
+)
+
+// dciMaxJobRuntime is how long after its creation a DCI job stored while running is
+// still synced again, so that a job DCI never closes does not hold every sync back.
+const dciMaxJobRuntime = 7 * 24 * time.Hour
+
+// dciFinalStatuses are the statuses of the DCI jobs that have finished.
+var dciFinalStatuses = map[string]bool{
+	"success": true,
+	"failure": true,
+	"error":   true,
+	"killed":  true,
+}
+
+// setJobStatus records the status, timestamps and outcome of a DCI job.
+// It must be called once the certsuite totals of the job are summed.
+func (j *DciJob) setJobStatus(job dci.Job) {
+	j.State = job.State
+	j.Status = job.Status
+	j.StatusReason = job.StatusReason
+	j.StartedAt = job.CreatedAt
+	j.Duration = job.Duration
+
+	finished := dciFinalStatuses[job.Status]
+	if finished {
+		// A finished job is no longer updated, so its last update is its end
+		j.EndedAt = job.UpdatedAt
+		if j.Duration == 0 {
+			started, startErr := time.Parse(dciTimeFormat, job.CreatedAt)
+			ended, endErr := time.Parse(dciTimeFormat, job.UpdatedAt)
+			if startErr == nil && endErr == nil {
+				j.Duration = int(ended.Sub(started).Seconds())
+			}
+		}
+	}
+
+	switch {
+	case j.TotalSuccess+j.TotalFailures+j.TotalErrors+j.TotalSkips > 0:
+		j.Outcome = JobOutcomeTested
+	case finished:
+		j.Outcome = JobOutcomeInfrastructureFailure
+	default:
+		j.Outcome = JobOutcomeRunning
+	}
+}
+
+// DciIncrementalWindow returns the incremental window of DCI, moved back to the creation
+// of the oldest job that was still running when stored, so that its final status and
+// results are picked up even once it is older than the overlap.
+func DciIncrementalWindow(store Store, overlap time.Duration, fallback Window) (Window, error) {
+	window, err := IncrementalWindow(store, DciSource, overlap, fallback)
+	if err != nil {
+		return window, err
+	}
+	unfinished, err := store.GetUnfinishedDciJobs()
+	if err != nil {
+		return window, fmt.Errorf("failed to read unfinished DCI jobs: %w", err)
+	}
+
+	oldestAllowed := window.Until.Add(-dciMaxJobRuntime)
+	for _, job := range unfinished {
+		createdAt, err := time.Parse(dciTimeFormat, job.CreatedAt)
+		if err != nil || createdAt.Before(oldestAllowed) || !createdAt.Before(window.Since) {
+			continue
+		}
+		log.Printf("Syncing DCI from %s to refresh job %s, which was still running", createdAt.Format(time.RFC3339), job.JobID)
+		window.Since = createdAt
+	}
+	return window, nil
+}
+
+// GetUnfinishedDciJobs returns the ID and creation time of the stored DCI jobs that had not
+// finished when they were stored, oldest first. Rows stored before job statuses were
+// recorded have no status and are not reported.
+func (s *sqlStore) GetUnfinishedDciJobs() ([]DciJob, error) {
+	rows, err := s.db.Query(`SELECT job_id, createdAt FROM dci_components
+		WHERE ended_at IS NULL AND status <> '' ORDER BY createdAt, job_id;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unfinished DCI jobs: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close dci_components rows: %v", err)
+		}
+	}()
+
+	var jobs []DciJob
+	for rows.Next() {
+		var job DciJob
+		var createdAt dbTime
+		if err := rows.Scan(&job.JobID, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dci_components row: %w", err)
+		}
+		job.CreatedAt = createdAt.Format(dciTimeFormat)
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
@@ -0,0 +1,103 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertsuiteJobsStatus(t *testing.T) {
+	runs := dciRunsFromJSON(t, `{"jobs": [
+		{
+			"id": "job-1",
+			"created_at": "2024-11-26T12:00:00",
+			"updated_at": "2024-11-26T12:45:00",
+			"status": "failure",
+			"components": [{"name": "certsuite v5.2.1"}],
+			"results": [{"name": "certsuite-tests_junit.xml", "success": 10, "failures": 2}]
+		},
+		{
+			"id": "job-2",
+			"created_at": "2024-11-26T12:00:00",
+			"updated_at": "2024-11-26T12:10:00",
+			"status": "error",
+			"status_reason": "cluster install failed",
+			"components": [{"name": "certsuite v5.2.1"}]
+		},
+		{
+			"id": "job-3",
+			"created_at": "2024-11-26T12:00:00",
+			"updated_at": "2024-11-26T12:05:00",
+			"status": "running",
+			"components": [{"name": "certsuite v5.2.1"}]
+		}
+	]}`)
+
+	jobs := certsuiteJobs(runs, novemberWindow, testDciOptions)
+	require.Len(t, jobs, 3)
+
+	assert.Equal(t, JobOutcomeTested, jobs[0].Outcome)
+	assert.Equal(t, "2024-11-26T12:45:00", jobs[0].EndedAt)
+	// DCI reported no duration, so it is computed from the timestamps
+	assert.Equal(t, 45*60, jobs[0].Duration)
+
+	assert.Equal(t, JobOutcomeInfrastructureFailure, jobs[1].Outcome)
+	assert.Equal(t, "cluster install failed", jobs[1].StatusReason)
+
+	assert.Equal(t, JobOutcomeRunning, jobs[2].Outcome)
+	assert.Empty(t, jobs[2].EndedAt)
+	assert.Zero(t, jobs[2].Duration)
+}
+
+func TestJobStatusRoundTrip(t *testing.T) {
+	store := newSQLiteStore(t)
+	finished := DciJob{JobID: "job-1", CommitHash: "v5.2.1", CreatedAt: "2024-11-26T12:00:00",
+		Status: "error", StatusReason: "cluster install failed", StartedAt: "2024-11-26T12:00:00",
+		EndedAt: "2024-11-26T12:10:00", Duration: 600, Outcome: JobOutcomeInfrastructureFailure}
+	running := DciJob{JobID: "job-2", CommitHash: "v5.2.1", CreatedAt: "2024-11-26T13:00:00",
+		Status: "running", StartedAt: "2024-11-26T13:00:00", Outcome: JobOutcomeRunning}
+
+	for _, job := range []DciJob{finished, running} {
+		_, err := store.UpsertDciJob(job)
+		require.NoError(t, err)
+	}
+
+	jobs, err := store.GetDciJobs()
+	require.NoError(t, err)
+	assert.Equal(t, []DciJob{finished, running}, jobs)
+}
+
+func TestDciIncrementalWindow(t *testing.T) {
+	store := newSQLiteStore(t)
+	now := time.Date(2024, 11, 30, 12, 0, 0, 0, time.UTC)
+	fallback := Window{Since: now.AddDate(0, 0, -7), Until: now}
+	require.NoError(t, store.AdvanceWatermark(DciSource, now.Add(-time.Hour)))
+
+	for _, job := range []DciJob{
+		// Still running past the overlap, so the window goes back to it
+		{JobID: "job-1", CreatedAt: "2024-11-27T12:00:00", Status: "running", StartedAt: "2024-11-27T12:00:00", Outcome: JobOutcomeRunning},
+		{JobID: "job-2", CreatedAt: "2024-11-25T12:00:00", Status: "success", StartedAt: "2024-11-25T12:00:00",
+			EndedAt: "2024-11-25T13:00:00", Outcome: JobOutcomeTested},
+		// Too old to still be waited for
+		{JobID: "job-3", CreatedAt: "2024-11-20T12:00:00", Status: "running", StartedAt: "2024-11-20T12:00:00", Outcome: JobOutcomeRunning},
+		// Stored before job statuses were recorded
+		{JobID: "job-4", CreatedAt: "2024-11-26T12:00:00"},
+	} {
+		_, err := store.UpsertDciJob(job)
+		require.NoError(t, err)
+	}
+
+	window, err := DciIncrementalWindow(store, 24*time.Hour, fallback)
+	require.NoError(t, err)
+	assert.Equal(t, Window{Since: time.Date(2024, 11, 27, 12, 0, 0, 0, time.UTC), Until: now}, window)
+
+	// Once the job finished, the window starts from the watermark again
+	_, err = store.UpsertDciJob(DciJob{JobID: "job-1", CreatedAt: "2024-11-27T12:00:00", Status: "success",
+		StartedAt: "2024-11-27T12:00:00", EndedAt: "2024-11-28T12:00:00", Outcome: JobOutcomeTested})
+	require.NoError(t, err)
+	window, err = DciIncrementalWindow(store, 24*time.Hour, fallback)
+	require.NoError(t, err)
+	assert.Equal(t, Window{Since: now.Add(-25 * time.Hour), Until: now}, window)
+}
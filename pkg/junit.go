@@ -0,0 +1,97 @@
+package pkg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Outcomes of a JUnit test case.
+const (
+	TestCasePassed  = "passed"
+	TestCaseFailed  = "failed"
+	TestCaseError   = "error"
+	TestCaseSkipped = "skipped"
+)
+
+// junitTestSuites is the <testsuites> root of a JUnit report. Reports with a
+// single <testsuite> root are decoded into the same shape.
+type junitTestSuites struct {
+	Suites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+	Skipped   *junitMessage `xml:"skipped"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// text returns the message attribute, or the element body when there is none.
+func (m *junitMessage) text() string {
+	if m.Message != "" {
+		return m.Message
+	}
+	return strings.TrimSpace(m.Text)
+}
+
+// parseJUnit returns the test cases of a JUnit XML report as rows of the given job.
+func parseJUnit(jobID string, report []byte) ([]DciTestCase, error) {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(report, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse JUnit report: %w", err)
+	}
+
+	var suites junitTestSuites
+	switch root.XMLName.Local {
+	case "testsuites":
+		if err := xml.Unmarshal(report, &suites); err != nil {
+			return nil, fmt.Errorf("failed to parse JUnit report: %w", err)
+		}
+	case "testsuite":
+		var suite junitTestSuite
+		if err := xml.Unmarshal(report, &suite); err != nil {
+			return nil, fmt.Errorf("failed to parse JUnit report: %w", err)
+		}
+		suites.Suites = []junitTestSuite{suite}
+	default:
+		return nil, fmt.Errorf("unexpected JUnit root element <%s>", root.XMLName.Local)
+	}
+
+	var cases []DciTestCase
+	for _, suite := range suites.Suites {
+		for _, tc := range suite.TestCases {
+			testCase := DciTestCase{
+				JobID:    jobID,
+				Suite:    suite.Name,
+				Name:     tc.Name,
+				Status:   TestCasePassed,
+				Duration: tc.Time,
+			}
+			switch {
+			case tc.Failure != nil:
+				testCase.Status, testCase.Message = TestCaseFailed, tc.Failure.text()
+			case tc.Error != nil:
+				testCase.Status, testCase.Message = TestCaseError, tc.Error.text()
+			case tc.Skipped != nil:
+				testCase.Status, testCase.Message = TestCaseSkipped, tc.Skipped.text()
+			}
+			cases = append(cases, testCase)
+		}
+	}
+	return cases, nil
+}
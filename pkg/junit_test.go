@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJUnit(t *testing.T) {
+	tests := []struct {
+		name          string
+		report        string
+		expected      []DciTestCase
+		expectedError bool
+	}{
+		{
+			name: "Test suites root",
+			report: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="CNF Certification Test Suite" tests="4">
+    <testcase name="access-control-bpf-capability-check" time="0.5"></testcase>
+    <testcase name="networking-icmpv4-connectivity" time="12.25">
+      <failure message="pod unreachable">ping failed</failure>
+    </testcase>
+    <testcase name="platform-alteration-boot-params" time="1">
+      <error>panic: nil pointer</error>
+    </testcase>
+    <testcase name="lifecycle-crd-scaling" time="0">
+      <skipped message="no CRDs under test"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>`,
+			expected: []DciTestCase{
+				{JobID: "job-1", Suite: "CNF Certification Test Suite", Name: "access-control-bpf-capability-check", Status: TestCasePassed, Duration: 0.5},
+				{JobID: "job-1", Suite: "CNF Certification Test Suite", Name: "networking-icmpv4-connectivity", Status: TestCaseFailed, Duration: 12.25, Message: "pod unreachable"},
+				{JobID: "job-1", Suite: "CNF Certification Test Suite", Name: "platform-alteration-boot-params", Status: TestCaseError, Duration: 1, Message: "panic: nil pointer"},
+				{JobID: "job-1", Suite: "CNF Certification Test Suite", Name: "lifecycle-crd-scaling", Status: TestCaseSkipped, Message: "no CRDs under test"},
+			},
+		},
+		{
+			name:   "Single test suite root",
+			report: `<testsuite name="certsuite"><testcase name="observability-crd-status" time="2"/></testsuite>`,
+			expected: []DciTestCase{
+				{JobID: "job-1", Suite: "certsuite", Name: "observability-crd-status", Status: TestCasePassed, Duration: 2},
+			},
+		},
+		{
+			name:          "Not a JUnit report",
+			report:        `<html><body>Not found</body></html>`,
+			expectedError: true,
+		},
+		{
+			name:          "Invalid XML",
+			report:        `{"message": "not found"}`,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cases, err := parseJUnit("job-1", []byte(tc.report))
+
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, cases)
+		})
+	}
+}
@@ -0,0 +1,728 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// migration is a numbered, reversible change to the database schema.
+// Statements are built per dialect so each backend can use its own DDL.
+type migration struct {
+	version     int
+	description string
+	up          func(d dialect) []string
+	down        func(d dialect) []string
+}
+
+// migrations lists every schema change in the order it must be applied.
+// Append new migrations with the next version number; never edit an applied one.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create aggregated_logs and dci_components",
+		up: func(d dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS aggregated_logs (
+					datetime DATE NOT NULL,
+					count ` + d.unsignedInt + ` NOT NULL DEFAULT 0,
+					kind VARCHAR(255) NOT NULL,
+					PRIMARY KEY (datetime, kind)
+				);`,
+				`CREATE TABLE IF NOT EXISTS dci_components (
+					job_id VARCHAR(36) PRIMARY KEY,
+					commit_hash VARCHAR(255) NOT NULL,
+					createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					totalSuccess INT DEFAULT 0,
+					totalFailures INT DEFAULT 0,
+					totalErrors INT DEFAULT 0,
+					totalSkips INT DEFAULT 0
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{
+				`DROP TABLE IF EXISTS dci_components;`,
+				`DROP TABLE IF EXISTS aggregated_logs;`,
+			}
+		},
+	},
+	{
+		version:     2,
+		description: "create backfill_checkpoints",
+		up: func(dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS backfill_checkpoints (
+					range_since TIMESTAMP NOT NULL,
+					range_until TIMESTAMP NOT NULL,
+					completed_until TIMESTAMP NOT NULL,
+					updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY (range_since, range_until)
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{`DROP TABLE IF EXISTS backfill_checkpoints;`}
+		},
+	},
+	{
+		version:     3,
+		description: "create sync_state",
+		up: func(dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS sync_state (
+					source VARCHAR(255) PRIMARY KEY,
+					watermark TIMESTAMP NOT NULL,
+					updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{`DROP TABLE IF EXISTS sync_state;`}
+		},
+	},
+	{
+		version:     4,
+		description: "create sync_runs",
+		up: func(d dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS sync_runs (
+					id ` + d.autoIncrementKey + `,
+					source VARCHAR(255) NOT NULL,
+					started_at TIMESTAMP NOT NULL,
+					finished_at TIMESTAMP NOT NULL,
+					rows_inserted INT NOT NULL DEFAULT 0,
+					rows_updated INT NOT NULL DEFAULT 0,
+					error TEXT,
+					version VARCHAR(255) NOT NULL
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{`DROP TABLE IF EXISTS sync_runs;`}
+		},
+	},
+	{
+		version:     5,
+		description: "create dci_test_cases",
+		up: func(dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS dci_test_cases (
+					job_id VARCHAR(36) NOT NULL,
+					name VARCHAR(512) NOT NULL,
+					suite VARCHAR(255) NOT NULL DEFAULT '',
+					status VARCHAR(16) NOT NULL,
+					duration_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+					message TEXT,
+					PRIMARY KEY (job_id, name)
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{`DROP TABLE IF EXISTS dci_test_cases;`}
+		},
+	},
+	{
+		version:     6,
+		description: "add dci_components.ocp_version and create flaky_tests view",
+		up: func(dialect) []string {
+			return []string{
+				`ALTER TABLE dci_components ADD COLUMN ocp_version VARCHAR(64) NOT NULL DEFAULT '';`,
+				flakyTestsView(""),
+			}
+		},
+		down: func(dialect) []string {
+			return []string{
+				`DROP VIEW IF EXISTS flaky_tests;`,
+				`ALTER TABLE dci_components DROP COLUMN ocp_version;`,
+			}
+		},
+	},
+	{
+		version:     7,
+		description: "add certsuite product, version and release columns to dci_components",
+		up: func(dialect) []string {
+			return []string{
+				`ALTER TABLE dci_components ADD COLUMN product VARCHAR(64) NOT NULL DEFAULT '';`,
+				`ALTER TABLE dci_components ADD COLUMN certsuite_version VARCHAR(128) NOT NULL DEFAULT '';`,
+				`ALTER TABLE dci_components ADD COLUMN is_release BOOLEAN NOT NULL DEFAULT FALSE;`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{
+				`ALTER TABLE dci_components DROP COLUMN is_release;`,
+				`ALTER TABLE dci_components DROP COLUMN certsuite_version;`,
+				`ALTER TABLE dci_components DROP COLUMN product;`,
+			}
+		},
+	},
+	{
+		version:     8,
+		description: "add topic, remoteci_id and team_id to dci_components",
+		up: func(dialect) []string {
+			return []string{
+				`ALTER TABLE dci_components ADD COLUMN topic VARCHAR(255) NOT NULL DEFAULT '';`,
+				`ALTER TABLE dci_components ADD COLUMN remoteci_id VARCHAR(36) NOT NULL DEFAULT '';`,
+				`ALTER TABLE dci_components ADD COLUMN team_id VARCHAR(36) NOT NULL DEFAULT '';`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{
+				`ALTER TABLE dci_components DROP COLUMN team_id;`,
+				`ALTER TABLE dci_components DROP COLUMN remoteci_id;`,
+				`ALTER TABLE dci_components DROP COLUMN topic;`,
+			}
+		},
+	},
+	{
+		version:     9,
+		description: "create dci_teams",
+		up: func(dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS dci_teams (
+					team_id VARCHAR(36) NOT NULL,
+					remoteci_id VARCHAR(36) NOT NULL,
+					team_name VARCHAR(255) NOT NULL DEFAULT '',
+					remoteci_name VARCHAR(255) NOT NULL DEFAULT '',
+					external BOOLEAN NOT NULL DEFAULT FALSE,
+					last_seen TIMESTAMP NOT NULL,
+					PRIMARY KEY (team_id, remoteci_id)
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{`DROP TABLE IF EXISTS dci_teams;`}
+		},
+	},
+	{
+		version:     10,
+		description: "create dci_job_components",
+		up: func(dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS dci_job_components (
+					job_id VARCHAR(36) NOT NULL,
+					type VARCHAR(64) NOT NULL,
+					name VARCHAR(255) NOT NULL,
+					component_id VARCHAR(36) NOT NULL DEFAULT '',
+					version VARCHAR(255) NOT NULL DEFAULT '',
+					PRIMARY KEY (job_id, type, name)
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{`DROP TABLE IF EXISTS dci_job_components;`}
+		},
+	},
+	{
+		version:     11,
+		description: "create dci_job_results",
+		up: func(dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS dci_job_results (
+					job_id VARCHAR(36) NOT NULL,
+					file_name VARCHAR(255) NOT NULL,
+					file_id VARCHAR(36) NOT NULL DEFAULT '',
+					success INT NOT NULL DEFAULT 0,
+					failures INT NOT NULL DEFAULT 0,
+					errors INT NOT NULL DEFAULT 0,
+					skips INT NOT NULL DEFAULT 0,
+					total INT NOT NULL DEFAULT 0,
+					duration INT NOT NULL DEFAULT 0,
+					PRIMARY KEY (job_id, file_name)
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{`DROP TABLE IF EXISTS dci_job_results;`}
+		},
+	},
+	{
+		version:     12,
+		description: "add job status, timestamps, duration and outcome to dci_components",
+		up: func(dialect) []string {
+			return []string{
+				`ALTER TABLE dci_components ADD COLUMN state VARCHAR(64) NOT NULL DEFAULT '';`,
+				`ALTER TABLE dci_components ADD COLUMN status VARCHAR(64) NOT NULL DEFAULT '';`,
+				`ALTER TABLE dci_components ADD COLUMN status_reason VARCHAR(1024) NOT NULL DEFAULT '';`,
+				`ALTER TABLE dci_components ADD COLUMN started_at TIMESTAMP NULL;`,
+				`ALTER TABLE dci_components ADD COLUMN ended_at TIMESTAMP NULL;`,
+				`ALTER TABLE dci_components ADD COLUMN duration INT NOT NULL DEFAULT 0;`,
+				`ALTER TABLE dci_components ADD COLUMN outcome VARCHAR(64) NOT NULL DEFAULT '';`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{
+				`ALTER TABLE dci_components DROP COLUMN outcome;`,
+				`ALTER TABLE dci_components DROP COLUMN duration;`,
+				`ALTER TABLE dci_components DROP COLUMN ended_at;`,
+				`ALTER TABLE dci_components DROP COLUMN started_at;`,
+				`ALTER TABLE dci_components DROP COLUMN status_reason;`,
+				`ALTER TABLE dci_components DROP COLUMN status;`,
+				`ALTER TABLE dci_components DROP COLUMN state;`,
+			}
+		},
+	},
+	{
+		version:     13,
+		description: "add namespace and repository to the aggregated_logs key",
+		// No backend can alter a primary key in place, so the table is rebuilt. The rows
+		// synced before are left unassigned, for the Quay sync to attribute them.
+		up: func(d dialect) []string {
+			return []string{
+				`CREATE TABLE aggregated_logs_new (
+					datetime DATE NOT NULL,
+					namespace VARCHAR(255) NOT NULL,
+					repository VARCHAR(255) NOT NULL,
+					count ` + d.unsignedInt + ` NOT NULL DEFAULT 0,
+					kind VARCHAR(255) NOT NULL,
+					PRIMARY KEY (datetime, namespace, repository, kind)
+				);`,
+				`INSERT INTO aggregated_logs_new (datetime, namespace, repository, count, kind)
+				SELECT datetime, '', '', count, kind FROM aggregated_logs;`,
+				`DROP TABLE aggregated_logs;`,
+				`ALTER TABLE aggregated_logs_new RENAME TO aggregated_logs;`,
+			}
+		},
+		down: func(d dialect) []string {
+			return []string{
+				`CREATE TABLE aggregated_logs_old (
+					datetime DATE NOT NULL,
+					count ` + d.unsignedInt + ` NOT NULL DEFAULT 0,
+					kind VARCHAR(255) NOT NULL,
+					PRIMARY KEY (datetime, kind)
+				);`,
+				`INSERT INTO aggregated_logs_old (datetime, count, kind)
+				SELECT datetime, SUM(count), kind FROM aggregated_logs GROUP BY datetime, kind;`,
+				`DROP TABLE aggregated_logs;`,
+				`ALTER TABLE aggregated_logs_old RENAME TO aggregated_logs;`,
+			}
+		},
+	},
+	{
+		version:     14,
+		description: "create quay_tag_pulls",
+		up: func(d dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS quay_tag_pulls (
+					datetime DATE NOT NULL,
+					namespace VARCHAR(255) NOT NULL,
+					repository VARCHAR(255) NOT NULL,
+					tag VARCHAR(255) NOT NULL,
+					count ` + d.unsignedInt + ` NOT NULL DEFAULT 0,
+					PRIMARY KEY (datetime, namespace, repository, tag)
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{`DROP TABLE IF EXISTS quay_tag_pulls;`}
+		},
+	},
+	{
+		version:     15,
+		description: "create quay_tags",
+		up: func(dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS quay_tags (
+					snapshot_date DATE NOT NULL,
+					namespace VARCHAR(255) NOT NULL,
+					repository VARCHAR(255) NOT NULL,
+					tag VARCHAR(255) NOT NULL,
+					manifest_digest VARCHAR(255) NOT NULL DEFAULT '',
+					is_manifest_list BOOLEAN NOT NULL DEFAULT FALSE,
+					size BIGINT NOT NULL DEFAULT 0,
+					last_modified TIMESTAMP NULL,
+					expiration TIMESTAMP NULL,
+					PRIMARY KEY (snapshot_date, namespace, repository, tag)
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{`DROP TABLE IF EXISTS quay_tags;`}
+		},
+	},
+	{
+		version:     16,
+		description: "create quay_vulnerabilities",
+		up: func(dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS quay_vulnerabilities (
+					snapshot_date DATE NOT NULL,
+					namespace VARCHAR(255) NOT NULL,
+					repository VARCHAR(255) NOT NULL,
+					tag VARCHAR(255) NOT NULL,
+					manifest_digest VARCHAR(255) NOT NULL DEFAULT '',
+					status VARCHAR(64) NOT NULL DEFAULT '',
+					critical INT NOT NULL DEFAULT 0,
+					high INT NOT NULL DEFAULT 0,
+					medium INT NOT NULL DEFAULT 0,
+					low INT NOT NULL DEFAULT 0,
+					negligible INT NOT NULL DEFAULT 0,
+					unknown INT NOT NULL DEFAULT 0,
+					fixable INT NOT NULL DEFAULT 0,
+					total INT NOT NULL DEFAULT 0,
+					PRIMARY KEY (snapshot_date, namespace, repository, tag)
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{`DROP TABLE IF EXISTS quay_vulnerabilities;`}
+		},
+	},
+	{
+		version:     17,
+		description: "create quay_pull_breakdown",
+		up: func(d dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS quay_pull_breakdown (
+					datetime DATE NOT NULL,
+					namespace VARCHAR(255) NOT NULL,
+					repository VARCHAR(255) NOT NULL,
+					client VARCHAR(64) NOT NULL,
+					region VARCHAR(64) NOT NULL,
+					count ` + d.unsignedInt + ` NOT NULL DEFAULT 0,
+					PRIMARY KEY (datetime, namespace, repository, client, region)
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{`DROP TABLE IF EXISTS quay_pull_breakdown;`}
+		},
+	},
+	{
+		version:     18,
+		description: "create quay_pull_performers",
+		up: func(d dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS quay_pull_performers (
+					datetime DATE NOT NULL,
+					namespace VARCHAR(255) NOT NULL,
+					repository VARCHAR(255) NOT NULL,
+					kind VARCHAR(32) NOT NULL,
+					name VARCHAR(255) NOT NULL DEFAULT '',
+					count ` + d.unsignedInt + ` NOT NULL DEFAULT 0,
+					PRIMARY KEY (datetime, namespace, repository, kind, name)
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{`DROP TABLE IF EXISTS quay_pull_performers;`}
+		},
+	},
+	{
+		version:     19,
+		description: "add rows_written to sync_runs",
+		up: func(dialect) []string {
+			return []string{`ALTER TABLE sync_runs ADD COLUMN rows_written INT NOT NULL DEFAULT 0;`}
+		},
+		down: func(dialect) []string {
+			return []string{`ALTER TABLE sync_runs DROP COLUMN rows_written;`}
+		},
+	},
+	{
+		version:     20,
+		description: "create dci_pending_files",
+		up: func(dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS dci_pending_files (
+					job_id VARCHAR(36) NOT NULL,
+					file_id VARCHAR(36) NOT NULL,
+					attempts INT NOT NULL DEFAULT 0,
+					PRIMARY KEY (job_id, file_id)
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{`DROP TABLE IF EXISTS dci_pending_files;`}
+		},
+	},
+	{
+		version:     21,
+		description: "restrict flaky_tests to jobs with a known commit and OCP version",
+		up: func(dialect) []string {
+			return []string{
+				`DROP VIEW IF EXISTS flaky_tests;`,
+				// Older rows hold the version or "unknown" in commit_hash when the commit is unknown
+				flakyTestsView(`AND j.commit_hash NOT IN ('', 'unknown') AND j.commit_hash <> j.certsuite_version
+					AND j.ocp_version <> ''`),
+			}
+		},
+		down: func(dialect) []string {
+			return []string{
+				`DROP VIEW IF EXISTS flaky_tests;`,
+				flakyTestsView(""),
+			}
+		},
+	},
+	{
+		version:     22,
+		description: "clear dci_components.commit_hash of builds without a known commit",
+		up: func(dialect) []string {
+			// Older releases stored the version, or "unknown" without one, when the commit was unknown
+			return []string{
+				`UPDATE dci_components SET commit_hash = '' WHERE commit_hash = 'unknown' OR commit_hash = certsuite_version;`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{
+				`UPDATE dci_components SET commit_hash = CASE WHEN certsuite_version <> '' THEN certsuite_version ELSE 'unknown' END
+				WHERE commit_hash = '';`,
+			}
+		},
+	},
+	{
+		version:     23,
+		description: "narrow quay_pull_performers.kind and name",
+		// The primary key of migration 18 exceeds the 3072 bytes of a MySQL index in utf8mb4
+		up: func(d dialect) []string {
+			return append(d.alterColumnType("quay_pull_performers", "kind", "VARCHAR(16)", "NOT NULL"),
+				d.alterColumnType("quay_pull_performers", "name", "VARCHAR(128)", "NOT NULL DEFAULT ''")...)
+		},
+		down: func(d dialect) []string {
+			return append(d.alterColumnType("quay_pull_performers", "kind", "VARCHAR(32)", "NOT NULL"),
+				d.alterColumnType("quay_pull_performers", "name", "VARCHAR(255)", "NOT NULL DEFAULT ''")...)
+		},
+	},
+	{
+		version:     24,
+		description: "create sync_settings",
+		up: func(dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS sync_settings (
+					name VARCHAR(64) PRIMARY KEY,
+					value VARCHAR(255) NOT NULL
+				);`,
+			}
+		},
+		down: func(dialect) []string {
+			return []string{`DROP TABLE IF EXISTS sync_settings;`}
+		},
+	},
+}
+
+// flakyTestsView creates the flaky_tests view, with one row per test case whose outcome
+// differs between jobs on the same commit and OCP version. filter further restricts the
+// jobs considered.
+func flakyTestsView(filter string) string {
+	return `CREATE VIEW flaky_tests AS
+				SELECT c.name, j.commit_hash, j.ocp_version,
+					SUM(CASE WHEN c.status = 'passed' THEN 1 ELSE 0 END) AS passes,
+					SUM(CASE WHEN c.status IN ('failed', 'error') THEN 1 ELSE 0 END) AS failures,
+					MAX(j.createdAt) AS last_seen
+				FROM dci_test_cases c
+				JOIN dci_components j ON j.job_id = c.job_id
+				WHERE c.status <> 'skipped' ` + filter + `
+				GROUP BY c.name, j.commit_hash, j.ocp_version
+				HAVING SUM(CASE WHEN c.status = 'passed' THEN 1 ELSE 0 END) > 0
+					AND SUM(CASE WHEN c.status IN ('failed', 'error') THEN 1 ELSE 0 END) > 0;`
+}
+
+// latestSchemaVersion is the newest schema version known to this binary.
+func latestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// MigrationStatus describes a schema migration and whether it is applied.
+type MigrationStatus struct {
+	Version     int
+	Description string
+	Applied     bool
+	AppliedAt   string
+	// Known is false for migrations recorded by a newer binary.
+	Known bool
+}
+
+// Migrator applies and reverts the schema migrations of a database.
+type Migrator struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// OpenMigrator connects to the database selected by DB_CHOICE without changing its schema.
+func OpenMigrator() (*Migrator, error) {
+	db, d, err := connectDatabase()
+	if err != nil {
+		return nil, err
+	}
+	m := &Migrator{db: db, dialect: d}
+	if err := m.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Close releases the underlying connection.
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// ensureMigrationsTable creates the schema_migrations table if it does not exist.
+func (m *Migrator) ensureMigrationsTable() error {
+	_, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		description VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// currentVersion returns the highest applied schema version, or 0 for an empty database.
+func (m *Migrator) currentVersion() (int, error) {
+	var version sql.NullInt64
+	if err := m.db.QueryRow(`SELECT MAX(version) FROM schema_migrations;`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// checkVersion refuses to work on a schema migrated by a newer binary.
+func (m *Migrator) checkVersion() (int, error) {
+	current, err := m.currentVersion()
+	if err != nil {
+		return 0, err
+	}
+	if current > latestSchemaVersion() {
+		return 0, fmt.Errorf("database schema version %d is newer than the latest version %d known to this binary, please upgrade certsuite-overview",
+			current, latestSchemaVersion())
+	}
+	return current, nil
+}
+
+// Up applies every pending migration and returns the versions it applied.
+func (m *Migrator) Up() ([]int, error) {
+	current, err := m.checkVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []int
+	for _, mig := range migrations {
+		if mig.version <= current {
+			continue
+		}
+		logrus.Infof("Applying migration %d: %s", mig.version, mig.description)
+		record := m.dialect.rebind(`INSERT INTO schema_migrations (version, description) VALUES (?, ?);`)
+		if err := m.run(mig.up(m.dialect), record, mig.version, mig.description); err != nil {
+			return applied, fmt.Errorf("failed to apply migration %d: %w", mig.version, err)
+		}
+		applied = append(applied, mig.version)
+	}
+	return applied, nil
+}
+
+// Down reverts the most recently applied migration and returns its version, or 0 if none was applied.
+func (m *Migrator) Down() (int, error) {
+	current, err := m.checkVersion()
+	if err != nil {
+		return 0, err
+	}
+	if current == 0 {
+		return 0, nil
+	}
+
+	for _, mig := range migrations {
+		if mig.version != current {
+			continue
+		}
+		logrus.Infof("Reverting migration %d: %s", mig.version, mig.description)
+		record := m.dialect.rebind(`DELETE FROM schema_migrations WHERE version = ?;`)
+		if err := m.run(mig.down(m.dialect), record, mig.version); err != nil {
+			return 0, fmt.Errorf("failed to revert migration %d: %w", mig.version, err)
+		}
+		return mig.version, nil
+	}
+	return 0, fmt.Errorf("applied migration %d is unknown to this binary", current)
+}
+
+// run executes the statements of a migration followed by its schema_migrations bookkeeping.
+// MySQL commits DDL implicitly, so the transaction only guarantees atomicity on SQLite and PostgreSQL.
+func (m *Migrator) run(statements []string, record string, args ...any) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	rollback := func(err error) error {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logrus.Errorf("failed to roll back migration: %v", rbErr)
+		}
+		return err
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return rollback(err)
+		}
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		return rollback(err)
+	}
+	return tx.Commit()
+}
+
+// Status lists every known migration plus any applied migration unknown to this binary.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	rows, err := m.db.Query(`SELECT version, description, applied_at FROM schema_migrations ORDER BY version;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close schema_migrations rows: %v", err)
+		}
+	}()
+
+	applied := map[int]MigrationStatus{}
+	for rows.Next() {
+		var status MigrationStatus
+		var appliedAt dbTime
+		if err := rows.Scan(&status.Version, &status.Description, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations row: %w", err)
+		}
+		status.Applied = true
+		status.AppliedAt = appliedAt.Format("2006-01-02 15:04:05")
+		applied[status.Version] = status
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, mig := range migrations {
+		status, ok := applied[mig.version]
+		if !ok {
+			status = MigrationStatus{Version: mig.version, Description: mig.description}
+		}
+		status.Known = true
+		statuses = append(statuses, status)
+		delete(applied, mig.version)
+	}
+	unknown := make([]int, 0, len(applied))
+	for version := range applied {
+		unknown = append(unknown, version)
+	}
+	sort.Ints(unknown)
+	for _, version := range unknown {
+		statuses = append(statuses, applied[version])
+	}
+	return statuses, nil
+}
+
+// prepareSchema applies pending migrations to a freshly opened database,
+// refusing to run against a schema newer than this binary knows.
+func prepareSchema(db *sql.DB, d dialect) error {
+	m := &Migrator{db: db, dialect: d}
+	if err := m.ensureMigrationsTable(); err != nil {
+		return err
+	}
+	applied, err := m.Up()
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		logrus.Infof("Applied schema migrations %v.", applied)
+	}
+	return nil
+}
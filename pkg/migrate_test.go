@@ -0,0 +1,133 @@
+package pkg
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSQLiteMigrator returns a Migrator on an empty SQLite file in a temporary directory.
+func newSQLiteMigrator(t *testing.T) *Migrator {
+	t.Helper()
+	db, err := connectDBSQLite(filepath.Join(t.TempDir(), "certsuite_usage.db"))
+	require.NoError(t, err)
+	m := &Migrator{db: db, dialect: sqliteDialect}
+	require.NoError(t, m.ensureMigrationsTable())
+	t.Cleanup(func() { assert.NoError(t, m.Close()) })
+	return m
+}
+
+func TestMigratorUpAndDown(t *testing.T) {
+	m := newSQLiteMigrator(t)
+
+	applied, err := m.Up()
+	require.NoError(t, err)
+	assert.Len(t, applied, len(migrations))
+
+	// Running up again is a no-op.
+	applied, err = m.Up()
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied, "migration %d should be applied", s.Version)
+	}
+
+	// Revert every migration, newest first.
+	for i := len(migrations) - 1; i >= 0; i-- {
+		reverted, err := m.Down()
+		require.NoError(t, err)
+		assert.Equal(t, migrations[i].version, reverted)
+	}
+	reverted, err := m.Down()
+	require.NoError(t, err)
+	assert.Zero(t, reverted)
+}
+
+func TestPrepareSchemaRefusesNewerSchema(t *testing.T) {
+	m := newSQLiteMigrator(t)
+
+	_, err := m.db.Exec(`INSERT INTO schema_migrations (version, description) VALUES (?, ?);`,
+		latestSchemaVersion()+1, "from the future")
+	require.NoError(t, err)
+
+	err = prepareSchema(m.db, sqliteDialect)
+	assert.ErrorContains(t, err, "newer than the latest version")
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	assert.False(t, statuses[len(statuses)-1].Known)
+}
+
+func TestAggregatedLogsRepositoryMigration(t *testing.T) {
+	m := newSQLiteMigrator(t)
+	_, err := m.Up()
+	require.NoError(t, err)
+	store := &sqlStore{db: m.db, dialect: sqliteDialect}
+	for _, aggregate := range []QuayAggregate{
+		{Datetime: "2024-11-26", Namespace: "ns", Repository: "certsuite", Count: 10, Kind: quayPullKind},
+		{Datetime: "2024-11-26", Namespace: "ns", Repository: "collector", Count: 5, Kind: quayPullKind},
+	} {
+		_, err := store.UpsertQuayAggregate(aggregate)
+		require.NoError(t, err)
+	}
+
+	// Reverting merges the repositories of each day
+	for reverted := 0; reverted != 13; {
+		reverted, err = m.Down()
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, reverted, 13)
+	}
+	var count int
+	require.NoError(t, m.db.QueryRow(`SELECT count FROM aggregated_logs WHERE kind = ?;`, quayPullKind).Scan(&count))
+	assert.Equal(t, 15, count)
+
+	// Re-applying leaves the merged rows unassigned, whatever the configuration
+	_, err = m.Up()
+	require.NoError(t, err)
+	aggregates, err := store.GetQuayAggregates()
+	require.NoError(t, err)
+	assert.Equal(t, []QuayAggregate{{Datetime: "2024-11-26", Count: 15, Kind: quayPullKind}}, aggregates)
+
+	// The Quay sync attributes them to the configured repository
+	assert.Error(t, assignLegacyQuayAggregates(store, "", ""))
+	require.NoError(t, assignLegacyQuayAggregates(store, "ns", "certsuite"))
+	aggregates, err = store.GetQuayAggregates()
+	require.NoError(t, err)
+	assert.Equal(t, []QuayAggregate{{Datetime: "2024-11-26", Namespace: "ns", Repository: "certsuite", Count: 15, Kind: quayPullKind}}, aggregates)
+}
+
+func TestCommitHashMigration(t *testing.T) {
+	m := newSQLiteMigrator(t)
+	_, err := m.Up()
+	require.NoError(t, err)
+	for reverted := 0; reverted != 22; {
+		reverted, err = m.Down()
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, reverted, 22)
+	}
+
+	// Rows stored by older releases fell back to the version, or to "unknown" without one
+	store := &sqlStore{db: m.db, dialect: sqliteDialect}
+	for _, job := range []DciJob{
+		{JobID: "job-1", CommitHash: "0a1b2c3", CertsuiteVersion: "v5.2.1", CreatedAt: "2024-11-26T12:00:00"},
+		{JobID: "job-2", CommitHash: "v5.2.1", CertsuiteVersion: "v5.2.1", CreatedAt: "2024-11-26T13:00:00"},
+		{JobID: "job-3", CommitHash: "unknown", CreatedAt: "2024-11-26T14:00:00"},
+	} {
+		_, err := store.UpsertDciJob(job)
+		require.NoError(t, err)
+	}
+
+	_, err = m.Up()
+	require.NoError(t, err)
+	jobs, err := store.GetDciJobs()
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+	assert.Equal(t, "0a1b2c3", jobs[0].CommitHash)
+	assert.Empty(t, jobs[1].CommitHash)
+	assert.Empty(t, jobs[2].CommitHash)
+}
@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	quay "github.com/sebrandon1/go-quay/lib"
+	"github.com/sirupsen/logrus"
+)
+
+// Kinds of performers of Quay pulls.
+const (
+	PerformerAnonymous = "anonymous"
+	PerformerUser      = "user"
+	PerformerRobot     = "robot"
+)
+
+// maxPerformerNameLength is the length of the quay_pull_performers name column, which
+// is bounded to fit the primary key in MySQL's index size limit.
+const maxPerformerNameLength = 128
+
+// QuayPullPerformer is the number of pulls of a Quay repository on one day by one kind
+// of performer. Name is only set when performer names are stored, and is always empty
+// for anonymous pulls.
+type QuayPullPerformer struct {
+	Datetime   string // Day of the pulls, formatted as YYYY-MM-DD.
+	Namespace  string
+	Repository string
+	Kind       string // One of the Performer constants.
+	Name       string
+	Count      int
+}
+
+// performerKind classifies the performer of a pull. Quay omits the performer of
+// anonymous pulls, and names robot accounts like namespace+robot.
+func performerKind(performer quay.Performer) string {
+	switch {
+	case performer.Name == "":
+		return PerformerAnonymous
+	case performer.IsRobot || performer.Kind == PerformerRobot || strings.Contains(performer.Name, "+"):
+		return PerformerRobot
+	default:
+		return PerformerUser
+	}
+}
+
+// quayPullPerformers counts the pulls of a repository by day and kind of performer,
+// ordered by day, kind and name. The names of the performers are only kept if storeNames is set.
+func quayPullPerformers(repository QuayRepository, pulls []quay.LogEntry, storeNames bool) ([]QuayPullPerformer, error) {
+	counts := map[QuayPullPerformer]int{}
+	for _, entry := range pulls {
+		pulledAt, err := time.Parse(quayDatetimeFormat, entry.Datetime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Quay datetime format: %v", entry.Datetime)
+		}
+		key := QuayPullPerformer{
+			Datetime:   pulledAt.UTC().Format("2006-01-02"),
+			Namespace:  repository.Namespace,
+			Repository: repository.Name,
+			Kind:       performerKind(entry.Performer),
+		}
+		if storeNames {
+			key.Name = truncate(entry.Performer.Name, maxPerformerNameLength)
+		}
+		counts[key]++
+	}
+
+	performers := make([]QuayPullPerformer, 0, len(counts))
+	for key, count := range counts {
+		key.Count = count
+		performers = append(performers, key)
+	}
+	sort.Slice(performers, func(i, j int) bool {
+		a, b := performers[i], performers[j]
+		if a.Datetime != b.Datetime {
+			return a.Datetime < b.Datetime
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return performers, nil
+}
+
+// truncate returns s cut to at most n runes.
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+// storeQuayPullPerformers replaces the pull counts by kind of performer of a repository
+// on the days from since to until, both inclusive, which the pulls were read for.
+func storeQuayPullPerformers(store Store, repository QuayRepository, since, until string, performers []QuayPullPerformer) (int, error) {
+	if err := store.ReplaceQuayPullPerformers(repository, since, until, performers); err != nil {
+		return 0, fmt.Errorf("failed to store pulls of %s by performer from %s to %s: %w", repository, since, until, err)
+	}
+	log.Printf("Stored %d daily pull counts by performer", len(performers))
+	return len(performers), nil
+}
+
+func (s *sqlStore) ReplaceQuayPullPerformers(repository QuayRepository, since, until string, performers []QuayPullPerformer) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	rollback := func(err error) error {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logrus.Errorf("failed to roll back pulls by performer of %s: %v", repository, rbErr)
+		}
+		return err
+	}
+
+	// Rows of the other naming mode, after QUAY_STORE_PERFORMER_NAMES changed, must not be counted twice
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM quay_pull_performers
+		WHERE namespace = ? AND repository = ? AND datetime >= ? AND datetime <= ?;`),
+		repository.Namespace, repository.Name, since, until); err != nil {
+		return rollback(err)
+	}
+	insert := s.dialect.rebind(`INSERT INTO quay_pull_performers (datetime, namespace, repository, kind, name, count)
+		VALUES (?, ?, ?, ?, ?, ?);`)
+	for _, p := range performers {
+		if _, err := tx.Exec(insert, p.Datetime, p.Namespace, p.Repository, p.Kind, p.Name, p.Count); err != nil {
+			return rollback(err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetQuayPullPerformers returns every stored pull count by performer, ordered by day, repository, kind and name.
+func (s *sqlStore) GetQuayPullPerformers() ([]QuayPullPerformer, error) {
+	rows, err := s.db.Query(`SELECT datetime, namespace, repository, kind, name, count FROM quay_pull_performers
+		ORDER BY datetime, namespace, repository, kind, name;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quay_pull_performers: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close quay_pull_performers rows: %v", err)
+		}
+	}()
+
+	var performers []QuayPullPerformer
+	for rows.Next() {
+		var p QuayPullPerformer
+		var datetime dbTime
+		if err := rows.Scan(&datetime, &p.Namespace, &p.Repository, &p.Kind, &p.Name, &p.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan quay_pull_performers row: %w", err)
+		}
+		p.Datetime = datetime.Format("2006-01-02")
+		performers = append(performers, p)
+	}
+	return performers, rows.Err()
+}
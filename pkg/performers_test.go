@@ -0,0 +1,79 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+
+	quay "github.com/sebrandon1/go-quay/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerformerKind(t *testing.T) {
+	tests := []struct {
+		performer quay.Performer
+		expected  string
+	}{
+		{quay.Performer{}, PerformerAnonymous},
+		{quay.Performer{Kind: "user", Name: "jdoe"}, PerformerUser},
+		{quay.Performer{Kind: "user", Name: "redhat-best-practices-for-k8s+ci", IsRobot: true}, PerformerRobot},
+		{quay.Performer{Name: "redhat+dci_agent"}, PerformerRobot},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, performerKind(tc.performer), "performer %+v", tc.performer)
+	}
+}
+
+func TestQuayPullPerformers(t *testing.T) {
+	pulls := []quay.LogEntry{
+		{Datetime: "Tue, 26 Nov 2024 10:00:00 -0000"},
+		{Datetime: "Tue, 26 Nov 2024 11:00:00 -0000"},
+		{Datetime: "Tue, 26 Nov 2024 12:00:00 -0000", Performer: quay.Performer{Kind: "user", Name: "jdoe"}},
+		{Datetime: "Tue, 26 Nov 2024 13:00:00 -0000", Performer: quay.Performer{Kind: "user", Name: "asmith"}},
+		{Datetime: "Wed, 27 Nov 2024 09:00:00 -0000", Performer: quay.Performer{Kind: "user", Name: "redhat+dci_agent", IsRobot: true}},
+	}
+
+	// Without the names, the users of a day are counted together
+	performers, err := quayPullPerformers(certsuiteRepository, pulls, false)
+	require.NoError(t, err)
+	assert.Equal(t, []QuayPullPerformer{
+		{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Kind: PerformerAnonymous, Count: 2},
+		{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Kind: PerformerUser, Count: 2},
+		{Datetime: "2024-11-27", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Kind: PerformerRobot, Count: 1},
+	}, performers)
+
+	named, err := quayPullPerformers(certsuiteRepository, pulls, true)
+	require.NoError(t, err)
+	assert.Equal(t, []QuayPullPerformer{
+		{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Kind: PerformerAnonymous, Count: 2},
+		{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Kind: PerformerUser, Name: "asmith", Count: 1},
+		{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Kind: PerformerUser, Name: "jdoe", Count: 1},
+		{Datetime: "2024-11-27", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Kind: PerformerRobot, Name: "redhat+dci_agent", Count: 1},
+	}, named)
+
+	// A day outside the synced days is kept
+	store := newSQLiteStore(t)
+	earlier := QuayPullPerformer{Datetime: "2024-11-25", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Kind: PerformerAnonymous, Count: 4}
+	_, err = storeQuayPullPerformers(store, certsuiteRepository, "2024-11-25", "2024-11-25", []QuayPullPerformer{earlier})
+	require.NoError(t, err)
+
+	// Turning the names off replaces the named rows of the synced days instead of adding to them
+	_, err = storeQuayPullPerformers(store, certsuiteRepository, "2024-11-26", "2024-11-27", named)
+	require.NoError(t, err)
+	written, err := storeQuayPullPerformers(store, certsuiteRepository, "2024-11-26", "2024-11-27", performers)
+	require.NoError(t, err)
+	assert.Equal(t, len(performers), written)
+	stored, err := store.GetQuayPullPerformers()
+	require.NoError(t, err)
+	assert.Equal(t, append([]QuayPullPerformer{earlier}, performers...), stored)
+}
+
+func TestQuayPullPerformersTruncatesNames(t *testing.T) {
+	name := "redhat-best-practices-for-k8s+" + strings.Repeat("r", 200)
+	pulls := []quay.LogEntry{{Datetime: "Tue, 26 Nov 2024 10:00:00 -0000", Performer: quay.Performer{Name: name, IsRobot: true}}}
+
+	performers, err := quayPullPerformers(certsuiteRepository, pulls, true)
+	require.NoError(t, err)
+	assert.Equal(t, name[:maxPerformerNameLength], performers[0].Name)
+}
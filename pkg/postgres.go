@@ -0,0 +1,82 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/lib/pq"
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/config"
+	"github.com/sirupsen/logrus"
+)
+
+// postgresDriver is the database/sql driver PostgreSQL connections are opened with.
+var postgresDriver = "postgres"
+
+// postgresDSN builds the connection string to dbName on the configured PostgreSQL server.
+func postgresDSN(dbName string) string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		config.AppConfig.DBURL, config.AppConfig.DBPort, config.AppConfig.DBUser, config.AppConfig.DBPassword,
+		dbName, config.AppConfig.DBSSLMode)
+}
+
+// ConnectToPostgresDB connects to the PostgreSQL server, creating the certsuite database if needed.
+func ConnectToPostgresDB() (*sql.DB, error) {
+	const dbName = "certsuite_usage_db"
+
+	logrus.Info("Opening connection to PostgreSQL server...")
+
+	// Initial connection to the maintenance database (to check database existence)
+	adminDB, err := sql.Open(postgresDriver, postgresDSN("postgres"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL server: %w", err)
+	}
+	defer func() {
+		if err := adminDB.Close(); err != nil {
+			logrus.Errorf("failed to close PostgreSQL connection: %v", err)
+		}
+	}()
+
+	// PostgreSQL has no CREATE DATABASE IF NOT EXISTS, so check the catalog first
+	var exists int
+	err = adminDB.QueryRow("SELECT COUNT(*) FROM pg_database WHERE datname = $1", dbName).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if database exists: %w", err)
+	}
+
+	if exists == 0 {
+		logrus.Infof("Database '%s' does not exist, creating...", dbName)
+		if _, err := adminDB.Exec("CREATE DATABASE " + dbName); err != nil {
+			return nil, fmt.Errorf("failed to create database: %w", err)
+		}
+		logrus.Infof("Database '%s' created successfully.", dbName)
+	} else {
+		logrus.Infof("Database '%s' already exists, skipping creation.", dbName)
+	}
+
+	// Connect to the newly ensured database
+	db, err := sql.Open(postgresDriver, postgresDSN(dbName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL database '%s': %w", dbName, err)
+	}
+
+	if err := pingDB(db); err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL database '%s': %w", dbName, err)
+	}
+
+	logrus.Infof("Successfully connected to PostgreSQL database '%s'.", dbName)
+	return db, nil
+}
+
+func initDBPostgres() (*sql.DB, error) {
+	db, err := ConnectToPostgresDB()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := prepareSchema(db, postgresDialect); err != nil {
+		return nil, fmt.Errorf("failed to prepare schema of PostgreSQL database: %w", err)
+	}
+
+	logrus.Info("PostgreSQL database initialized successfully.")
+	return db, nil
+}
@@ -0,0 +1,42 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectToPostgresDB(t *testing.T) {
+	saved, savedDriver := config.AppConfig, postgresDriver
+	t.Cleanup(func() { config.AppConfig, postgresDriver = saved, savedDriver })
+	config.AppConfig.DBURL = "localhost"
+	config.AppConfig.DBPort = "5432"
+	config.AppConfig.DBUser = "certsuite"
+	config.AppConfig.DBSSLMode = "disable"
+	postgresDriver = "sqlmock"
+
+	_, admin, err := sqlmock.NewWithDSN(postgresDSN("postgres"))
+	require.NoError(t, err)
+	admin.ExpectQuery(`SELECT COUNT\(\*\) FROM pg_database`).WithArgs("certsuite_usage_db").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	admin.ExpectExec("CREATE DATABASE certsuite_usage_db").WillReturnResult(sqlmock.NewResult(0, 0))
+	admin.ExpectClose()
+
+	_, usage, err := sqlmock.NewWithDSN(postgresDSN("certsuite_usage_db"))
+	require.NoError(t, err)
+	usage.ExpectExec("SELECT 1").WillReturnResult(sqlmock.NewResult(0, 0))
+	usage.ExpectClose()
+
+	db, err := ConnectToPostgresDB()
+	require.NoError(t, err)
+
+	// Only the maintenance connection is closed, the returned one stays usable
+	_, err = db.Exec("SELECT 1")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	assert.NoError(t, admin.ExpectationsWereMet())
+	assert.NoError(t, usage.ExpectationsWereMet())
+}
@@ -3,6 +3,8 @@ package pkg
 import (
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 
@@ -12,42 +14,204 @@ import (
 
 const (
 	DateFormat = "01/02/2006"
+
+	// quayDatetimeFormat is the layout of the datetime field returned by the aggregatelogs endpoint.
+	quayDatetimeFormat = "Mon, 02 Jan 2006 15:04:05 -0700"
 )
 
-// fetchQuayData fetches the number of image pulls from Quay.
-func FetchQuayData() error {
-	// Initialize database connection
-	db, err := ChooseDatabase()
+// QuayRepository is a Quay image repository.
+type QuayRepository struct {
+	Namespace string
+	Name      string
+}
+
+func (r QuayRepository) String() string {
+	return r.Namespace + "/" + r.Name
+}
+
+// parseQuayRepositories parses a list of namespace/repository pairs.
+func parseQuayRepositories(values []string) ([]QuayRepository, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no Quay repository configured")
+	}
+	repositories := make([]QuayRepository, 0, len(values))
+	for _, value := range values {
+		namespace, name, ok := strings.Cut(value, "/")
+		if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid Quay repository %q, expected namespace/repository", value)
+		}
+		repositories = append(repositories, QuayRepository{Namespace: namespace, Name: name})
+	}
+	return repositories, nil
+}
+
+// FetchQuayData fetches the number of image pulls of every tracked repository from Quay for the days of the window,
+// and takes the day's snapshot of their tags and vulnerabilities.
+func FetchQuayData(store Store, window Window) (SyncResult, error) {
+	return fetchQuayData(store, window, true)
+}
+
+// fetchQuayData fetches the pulls of every tracked repository for the days of the window.
+// The tag and vulnerability snapshots only reflect the current state of the repositories,
+// so they are only taken when snapshot is set.
+func fetchQuayData(store Store, window Window, snapshot bool) (SyncResult, error) {
+	repositories, err := parseQuayRepositories(config.AppConfig.QuayRepositories)
 	if err != nil {
-		return err
+		return SyncResult{}, err
 	}
-	defer func() {
-		if closeErr := db.Close(); closeErr != nil {
-			log.Printf("Failed to close database connection: %v", closeErr)
+	scanTags := globs(config.AppConfig.QuayScanTags)
+	if err := scanTags.validate(); err != nil {
+		return SyncResult{}, err
+	}
+	if err := assignLegacyQuayAggregates(store, config.AppConfig.Namespace, config.AppConfig.Repository); err != nil {
+		return SyncResult{}, err
+	}
+
+	// Pulls are only broken down by client and region on request, and located by the GeoIP database if there is one
+	var locate regionLocator
+	if config.AppConfig.QuayPullBreakdown && config.AppConfig.GeoIPDatabase != "" {
+		var closeLocator func()
+		if locate, closeLocator, err = openGeoIPLocator(config.AppConfig.GeoIPDatabase); err != nil {
+			return SyncResult{}, err
 		}
-	}()
+		defer closeLocator()
+	}
 
 	// Initialize Quay client
 	quayClient, err := quay.NewClient(config.AppConfig.BearerToken)
 	if err != nil {
-		return fmt.Errorf("failed to initialize Quay client: %w", err)
+		return SyncResult{}, fmt.Errorf("failed to initialize Quay client: %w", err)
 	}
 
-	// Fetch aggregated logs from Quay
-	data, err := quayClient.GetAggregatedLogs(config.AppConfig.Namespace, config.AppConfig.Repository, "", "")
+	// Quay treats both dates as inclusive days, so end on the last day starting before Until
+	startDate := window.Since.Format(DateFormat)
+	endDate := window.Until.Add(-time.Nanosecond).Format(DateFormat)
+	firstDay, lastDay := window.Since.Format(windowDateFormat), window.Until.Add(-time.Nanosecond).Format(windowDateFormat)
+
+	snapshotDay := time.Now().UTC().Format("2006-01-02")
+	var result SyncResult
+	for _, repository := range repositories {
+		log.Printf("Fetching Quay data of %s from %s to %s", repository, startDate, endDate)
+
+		// Fetch aggregated logs from Quay
+		data, err := quayClient.GetAggregatedLogs(repository.Namespace, repository.Name, startDate, endDate)
+		if err != nil {
+			return result, fmt.Errorf("failed to fetch aggregated logs of %s from Quay: %w", repository, err)
+		}
+		repositoryResult, err := storeQuayAggregates(store, repository, data.Aggregated)
+		result.Inserted += repositoryResult.Inserted
+		result.Updated += repositoryResult.Updated
+		if err != nil {
+			return result, err
+		}
+
+		// The usage logs break the pulls down by tag
+		pulls, err := fetchQuayPullLogs(quayClient, repository, startDate, endDate)
+		if err != nil {
+			return result, err
+		}
+		tagPulls, err := quayTagPulls(repository, pulls)
+		if err != nil {
+			return result, err
+		}
+		written, err := storeQuayTagPulls(store, repository, firstDay, lastDay, tagPulls)
+		result.Written += written
+		if err != nil {
+			return result, err
+		}
+		if config.AppConfig.QuayPullBreakdown {
+			breakdown, err := quayPullBreakdown(repository, pulls, locate)
+			if err != nil {
+				return result, err
+			}
+			written, err := storeQuayPullBreakdown(store, repository, firstDay, lastDay, breakdown)
+			result.Written += written
+			if err != nil {
+				return result, err
+			}
+		}
+		performers, err := quayPullPerformers(repository, pulls, config.AppConfig.QuayStorePerformerNames)
+		if err != nil {
+			return result, err
+		}
+		written, err = storeQuayPullPerformers(store, repository, firstDay, lastDay, performers)
+		result.Written += written
+		if err != nil {
+			return result, err
+		}
+
+		// Take the day's inventory of the repository's tags
+		if snapshot {
+			tags, err := fetchQuayTags(quayClient, repository)
+			if err != nil {
+				return result, err
+			}
+			if err := store.ReplaceQuayTags(snapshotDay, repository, tags); err != nil {
+				return result, fmt.Errorf("failed to store tags of %s: %w", repository, err)
+			}
+			result.Written += len(tags)
+			vulnerabilities := fetchQuayVulnerabilities(quayClient, repository, tags, scanTags)
+			if err := store.ReplaceQuayVulnerabilities(snapshotDay, repository, vulnerabilities); err != nil {
+				return result, fmt.Errorf("failed to store vulnerabilities of %s: %w", repository, err)
+			}
+			result.Written += len(vulnerabilities)
+		}
+
+		// The watermark must not pass any repository's data, so the sync is only as recent as the least recent one
+		if !repositoryResult.Latest.IsZero() && (result.Latest.IsZero() || repositoryResult.Latest.Before(result.Latest)) {
+			result.Latest = repositoryResult.Latest
+		}
+	}
+	if err := advanceWatermark(store, QuaySource, result.Latest); err != nil {
+		return result, err
+	}
+	log.Println("Successfully fetched and stored Quay data.")
+	return result, nil
+}
+
+// assignLegacyQuayAggregates attributes the aggregates synced before aggregated_logs recorded
+// their repository to the main certsuite image, which was the only one synced then.
+func assignLegacyQuayAggregates(store Store, namespace, repository string) error {
+	if namespace == "" || repository == "" {
+		return fmt.Errorf("NAMESPACE and REPOSITORY must be set to attribute the Quay data synced before repositories were recorded")
+	}
+	assigned, err := store.AssignLegacyQuayAggregates(QuayRepository{Namespace: namespace, Name: repository})
 	if err != nil {
-		return fmt.Errorf("failed to fetch aggregated logs from Quay: %w", err)
+		return fmt.Errorf("failed to assign legacy Quay data to %s/%s: %w", namespace, repository, err)
+	}
+	if assigned > 0 {
+		log.Printf("Attributed %d Quay aggregates synced before repositories were recorded to %s/%s", assigned, namespace, repository)
 	}
-	// Loop through the aggregated data and insert it into the database
-	for _, aggregated := range data.Aggregated {
+	return nil
+}
+
+// storeQuayAggregates loops through the aggregated Quay data of a repository and saves it in the store.
+// The result's Latest is the latest day stored.
+func storeQuayAggregates(store Store, repository QuayRepository, entries []quay.AggregatedLogEntry) (SyncResult, error) {
+	var result SyncResult
+	for _, aggregated := range entries {
 		log.Println("Inserting Quay data into the database...")
 		log.Printf("Datetime: %s, Count: %d, Kind: %s", aggregated.Datetime, aggregated.Count, aggregated.Kind)
 		log.Println("--------------------")
-		if err = insertQuayData(db, aggregated.Datetime, aggregated.Count, aggregated.Kind); err != nil {
+
+		parsedDate, err := time.Parse(quayDatetimeFormat, aggregated.Datetime)
+		if err != nil {
+			return result, fmt.Errorf("invalid Quay datetime format: %v", aggregated.Datetime)
+		}
+
+		aggregate := QuayAggregate{
+			Datetime:   parsedDate.Format("2006-01-02"),
+			Namespace:  repository.Namespace,
+			Repository: repository.Name,
+			Count:      aggregated.Count,
+			Kind:       aggregated.Kind,
+		}
+		inserted, err := store.UpsertQuayAggregate(aggregate)
+		if err != nil {
 			log.Printf("Failed to insert Quay data (Datetime: %s, Count: %d, Kind: %s): %v", aggregated.Datetime, aggregated.Count, aggregated.Kind, err)
-			return fmt.Errorf("failed to insert Quay data: %w", err)
+			return result, fmt.Errorf("failed to insert Quay data: %w", err)
 		}
+		result.record(inserted, parsedDate.UTC())
 	}
-	log.Println("Successfully fetched and stored Quay data.")
-	return nil
+	return result, nil
 }
@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	quay "github.com/sebrandon1/go-quay/lib"
+	"github.com/stretchr/testify/assert"
+)
+
+// certsuiteRepository is the Quay repository of the test aggregates.
+var certsuiteRepository = QuayRepository{Namespace: "redhat-best-practices-for-k8s", Name: "certsuite"}
+
+func TestParseQuayRepositories(t *testing.T) {
+	repositories, err := parseQuayRepositories([]string{"redhat-best-practices-for-k8s/certsuite", "redhat-best-practices-for-k8s/certsuite-sample-workload"})
+	assert.NoError(t, err)
+	assert.Equal(t, []QuayRepository{
+		certsuiteRepository,
+		{Namespace: "redhat-best-practices-for-k8s", Name: "certsuite-sample-workload"},
+	}, repositories)
+
+	for _, invalid := range [][]string{nil, {"certsuite"}, {"/certsuite"}, {"a/b/c"}} {
+		_, err := parseQuayRepositories(invalid)
+		assert.Error(t, err, "%v", invalid)
+	}
+}
+
+func TestStoreQuayAggregates(t *testing.T) {
+	tests := []struct {
+		name          string
+		entries       []quay.AggregatedLogEntry
+		storeErr      error
+		expected      []QuayAggregate
+		expectedError bool
+	}{
+		{
+			name: "Quay datetime is stored as a day",
+			entries: []quay.AggregatedLogEntry{
+				{Kind: "pull_repo", Count: 42, Datetime: "Tue, 26 Nov 2024 00:00:00 -0000"},
+				{Kind: "push_repo", Count: 1, Datetime: "Wed, 27 Nov 2024 00:00:00 -0000"},
+			},
+			expected: []QuayAggregate{
+				{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Count: 42, Kind: "pull_repo"},
+				{Datetime: "2024-11-27", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Count: 1, Kind: "push_repo"},
+			},
+		},
+		{
+			name:          "Invalid datetime",
+			entries:       []quay.AggregatedLogEntry{{Kind: "pull_repo", Count: 1, Datetime: "2024-11-26"}},
+			expectedError: true,
+		},
+		{
+			name:          "Store error",
+			entries:       []quay.AggregatedLogEntry{{Kind: "pull_repo", Count: 1, Datetime: "Tue, 26 Nov 2024 00:00:00 -0000"}},
+			storeErr:      errors.New("boom"),
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeStore{err: tc.storeErr}
+
+			_, err := storeQuayAggregates(store, certsuiteRepository, tc.entries)
+
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, store.quayAggregates)
+		})
+	}
+}
+
+func TestStoreQuayAggregatesIsIdempotent(t *testing.T) {
+	store := newSQLiteStore(t)
+	entries := []quay.AggregatedLogEntry{
+		{Kind: "pull_repo", Count: 42, Datetime: "Tue, 26 Nov 2024 00:00:00 -0000"},
+		{Kind: "pull_repo", Count: 7, Datetime: "Wed, 27 Nov 2024 00:00:00 -0000"},
+	}
+
+	for run := 0; run < 3; run++ {
+		result, err := storeQuayAggregates(store, certsuiteRepository, entries)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 11, 27, 0, 0, 0, 0, time.UTC), result.Latest)
+		if run == 0 {
+			assert.Equal(t, 2, result.Inserted)
+		} else {
+			assert.Equal(t, 2, result.Updated)
+		}
+	}
+
+	aggregates, err := store.GetQuayAggregates()
+	assert.NoError(t, err)
+	assert.Equal(t, []QuayAggregate{
+		{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Count: 42, Kind: "pull_repo"},
+		{Datetime: "2024-11-27", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Count: 7, Kind: "pull_repo"},
+	}, aggregates)
+}
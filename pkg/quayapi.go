@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+
+	quay "github.com/sebrandon1/go-quay/lib"
+)
+
+// quayAPIURL is the base URL of the Quay API, overridden by tests.
+var quayAPIURL = quay.QuayURL
+
+// getQuayAPI decodes the JSON response of a GET request to a Quay API path into v.
+// It covers the endpoints and query parameters that the go-quay client lacks.
+func getQuayAPI(client *quay.Client, path string, query url.Values, v any) error {
+	req, err := http.NewRequest(http.MethodGet, quayAPIURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+client.BearerToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query Quay %s: %w", path, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Failed to close Quay %s response: %v", path, err)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s from Quay %s: %s", resp.Status, path, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode Quay %s response: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	quay "github.com/sebrandon1/go-quay/lib"
+	"github.com/sirupsen/logrus"
+)
+
+// quayTagsPageSize is the number of tags requested per page, the most Quay returns.
+const quayTagsPageSize = 100
+
+// QuayTag is a tag of a Quay repository as seen in a daily inventory snapshot.
+type QuayTag struct {
+	Namespace      string
+	Repository     string
+	Name           string
+	ManifestDigest string
+	IsManifestList bool
+	// Size is the compressed size of the image in bytes, 0 for manifest lists.
+	Size         int64
+	LastModified time.Time
+	// Expiration is when Quay deletes the tag, the zero time if it never expires.
+	Expiration time.Time
+}
+
+// fetchQuayTags returns the active tags of a repository, following every page.
+func fetchQuayTags(client *quay.Client, repository QuayRepository) ([]QuayTag, error) {
+	path := fmt.Sprintf("/repository/%s/%s/tag/", repository.Namespace, repository.Name)
+	query := url.Values{"onlyActiveTags": {"true"}, "limit": {strconv.Itoa(quayTagsPageSize)}}
+
+	var tags []QuayTag
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var response quay.RepositoryTags
+		if err := getQuayAPI(client, path, query, &response); err != nil {
+			return nil, fmt.Errorf("failed to fetch tags of %s: %w", repository, err)
+		}
+		for _, t := range response.Tags {
+			tag := QuayTag{
+				Namespace:      repository.Namespace,
+				Repository:     repository.Name,
+				Name:           t.Name,
+				ManifestDigest: t.ManifestDigest,
+				IsManifestList: t.IsManifestList,
+			}
+			// Quay reports no size for manifest lists
+			if size, ok := t.Size.(float64); ok {
+				tag.Size = int64(size)
+			}
+			var err error
+			if tag.LastModified, err = parseQuayTime(t.LastModified); err != nil {
+				return nil, fmt.Errorf("invalid last modification time of tag %s: %w", t.Name, err)
+			}
+			if tag.Expiration, err = parseQuayTime(t.Expiration); err != nil {
+				return nil, fmt.Errorf("invalid expiration time of tag %s: %w", t.Name, err)
+			}
+			tags = append(tags, tag)
+		}
+		if !response.HasAdditional {
+			log.Printf("Read %d tags of %s", len(tags), repository)
+			return tags, nil
+		}
+	}
+}
+
+// parseQuayTime parses a Quay API timestamp, returning the zero time for an empty one.
+func parseQuayTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(quayDatetimeFormat, value)
+	return parsed.UTC(), err
+}
+
+// nullableTimestamp returns t as a query argument, NULL if it is the zero time.
+func nullableTimestamp(t time.Time) sql.NullString {
+	return sql.NullString{String: t.UTC().Format(dbTimestampFormat), Valid: !t.IsZero()}
+}
+
+func (s *sqlStore) ReplaceQuayTags(day string, repository QuayRepository, tags []QuayTag) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	rollback := func(err error) error {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logrus.Errorf("failed to roll back tags of %s: %v", repository, rbErr)
+		}
+		return err
+	}
+
+	// Tags deleted since an earlier snapshot of the same day must not linger
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM quay_tags WHERE snapshot_date = ? AND namespace = ? AND repository = ?;`),
+		day, repository.Namespace, repository.Name); err != nil {
+		return rollback(err)
+	}
+	insert := s.dialect.rebind(`INSERT INTO quay_tags (snapshot_date, namespace, repository, tag, manifest_digest, is_manifest_list, size, last_modified, expiration)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`)
+	for _, t := range tags {
+		if _, err := tx.Exec(insert, day, t.Namespace, t.Repository, t.Name, t.ManifestDigest, t.IsManifestList, t.Size,
+			nullableTimestamp(t.LastModified), nullableTimestamp(t.Expiration)); err != nil {
+			return rollback(err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetQuayTags returns the tags of a repository in the snapshot of a day, ordered by name.
+func (s *sqlStore) GetQuayTags(day string, repository QuayRepository) ([]QuayTag, error) {
+	rows, err := s.db.Query(s.dialect.rebind(`
+		SELECT tag, manifest_digest, is_manifest_list, size, last_modified, expiration FROM quay_tags
+		WHERE snapshot_date = ? AND namespace = ? AND repository = ? ORDER BY tag;`), day, repository.Namespace, repository.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quay_tags: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close quay_tags rows: %v", err)
+		}
+	}()
+
+	var tags []QuayTag
+	for rows.Next() {
+		t := QuayTag{Namespace: repository.Namespace, Repository: repository.Name}
+		var lastModified, expiration dbTime
+		if err := rows.Scan(&t.Name, &t.ManifestDigest, &t.IsManifestList, &t.Size, &lastModified, &expiration); err != nil {
+			return nil, fmt.Errorf("failed to scan quay_tags row: %w", err)
+		}
+		t.LastModified, t.Expiration = lastModified.UTC(), expiration.UTC()
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
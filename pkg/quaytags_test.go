@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchQuayTags(t *testing.T) {
+	client := newQuayTestClient(t, map[string]http.HandlerFunc{
+		"/repository/redhat-best-practices-for-k8s/certsuite/tag/": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "true", r.URL.Query().Get("onlyActiveTags"))
+			switch r.URL.Query().Get("page") {
+			case "1":
+				_, _ = w.Write([]byte(`{"tags": [
+					{"name": "latest", "manifest_digest": "sha256:0a1b", "is_manifest_list": true, "size": null,
+					 "last_modified": "Tue, 26 Nov 2024 10:00:00 -0000"}
+				], "page": 1, "has_additional": true}`))
+			case "2":
+				_, _ = w.Write([]byte(`{"tags": [
+					{"name": "pr-42", "manifest_digest": "sha256:2c3d", "size": 123456789,
+					 "last_modified": "Mon, 25 Nov 2024 08:00:00 -0000", "expiration": "Mon, 09 Dec 2024 08:00:00 -0000"}
+				], "page": 2, "has_additional": false}`))
+			default:
+				t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+			}
+		},
+	})
+
+	tags, err := fetchQuayTags(client, certsuiteRepository)
+	require.NoError(t, err)
+	assert.Equal(t, []QuayTag{
+		{
+			Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Name: "latest",
+			ManifestDigest: "sha256:0a1b", IsManifestList: true,
+			LastModified: time.Date(2024, 11, 26, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Name: "pr-42",
+			ManifestDigest: "sha256:2c3d", Size: 123456789,
+			LastModified: time.Date(2024, 11, 25, 8, 0, 0, 0, time.UTC),
+			Expiration:   time.Date(2024, 12, 9, 8, 0, 0, 0, time.UTC),
+		},
+	}, tags)
+}
+
+func TestReplaceQuayTags(t *testing.T) {
+	store := newSQLiteStore(t)
+	latest := QuayTag{Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Name: "latest",
+		ManifestDigest: "sha256:0a1b", Size: 42, LastModified: time.Date(2024, 11, 26, 10, 0, 0, 0, time.UTC)}
+	pr := QuayTag{Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Name: "pr-42",
+		ManifestDigest: "sha256:2c3d", Size: 7, LastModified: time.Date(2024, 11, 25, 8, 0, 0, 0, time.UTC),
+		Expiration: time.Date(2024, 12, 9, 8, 0, 0, 0, time.UTC)}
+
+	require.NoError(t, store.ReplaceQuayTags("2024-11-26", certsuiteRepository, []QuayTag{latest, pr}))
+	// The tag deleted before the second snapshot of the day is dropped from it
+	require.NoError(t, store.ReplaceQuayTags("2024-11-26", certsuiteRepository, []QuayTag{latest}))
+	require.NoError(t, store.ReplaceQuayTags("2024-11-27", certsuiteRepository, []QuayTag{latest, pr}))
+
+	tags, err := store.GetQuayTags("2024-11-26", certsuiteRepository)
+	require.NoError(t, err)
+	assert.Equal(t, []QuayTag{latest}, tags)
+	tags, err = store.GetQuayTags("2024-11-27", certsuiteRepository)
+	require.NoError(t, err)
+	assert.Equal(t, []QuayTag{latest, pr}, tags)
+}
@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"fmt"
+	"log"
+
+	dci "github.com/sebrandon1/go-dci/lib"
+	"github.com/sirupsen/logrus"
+)
+
+// DciJobResult holds the results of one result file of a DCI job.
+type DciJobResult struct {
+	FileName string
+	FileID   string
+	Success  int
+	Failures int
+	Errors   int
+	Skips    int
+	Total    int
+	// Duration is the run time of the file's tests, as reported by DCI.
+	Duration int
+}
+
+// certsuiteJobResults returns the results of the tracked result files of the certsuite
+// DCI jobs created in the window, by job ID. Files reported several times for a job are summed.
+func certsuiteJobResults(runs []dci.JobsResponse, window Window, tracked globs) map[string][]DciJobResult {
+	results := map[string][]DciJobResult{}
+	forEachCertsuiteJob(runs, window, func(job dci.Job, _ CertsuiteComponent) {
+		index := map[string]int{}
+		jobResults := []DciJobResult{}
+		for _, r := range job.Results {
+			if !tracked.match(r.Name) {
+				continue
+			}
+			i, ok := index[r.Name]
+			if !ok {
+				i = len(jobResults)
+				index[r.Name] = i
+				jobResults = append(jobResults, DciJobResult{FileName: r.Name, FileID: r.FileID})
+			}
+			jobResults[i].Success += r.Success
+			jobResults[i].Failures += r.Failures
+			jobResults[i].Errors += r.Errors
+			jobResults[i].Skips += r.Skips
+			jobResults[i].Total += r.Total
+			jobResults[i].Duration += r.Time
+		}
+		results[job.ID] = jobResults
+	})
+	return results
+}
+
+// storeDciJobResults replaces the stored result files of every job.
+func storeDciJobResults(store Store, results map[string][]DciJobResult) (int, error) {
+	stored := 0
+	for jobID, jobResults := range results {
+		if err := store.ReplaceDciJobResults(jobID, jobResults); err != nil {
+			return stored, fmt.Errorf("failed to store result files of DCI job %s: %w", jobID, err)
+		}
+		stored += len(jobResults)
+	}
+	log.Printf("Stored %d result files of %d DCI jobs", stored, len(results))
+	return stored, nil
+}
+
+func (s *sqlStore) ReplaceDciJobResults(jobID string, results []DciJobResult) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	rollback := func(err error) error {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logrus.Errorf("failed to roll back result files of DCI job %s: %v", jobID, rbErr)
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM dci_job_results WHERE job_id = ?;`), jobID); err != nil {
+		return rollback(err)
+	}
+	insert := s.dialect.rebind(`INSERT INTO dci_job_results (job_id, file_name, file_id, success, failures, errors, skips, total, duration)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`)
+	for _, r := range results {
+		if _, err := tx.Exec(insert, jobID, r.FileName, r.FileID, r.Success, r.Failures, r.Errors, r.Skips, r.Total, r.Duration); err != nil {
+			return rollback(err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetDciJobResults returns the stored result files of a DCI job, ordered by file name.
+func (s *sqlStore) GetDciJobResults(jobID string) ([]DciJobResult, error) {
+	rows, err := s.db.Query(s.dialect.rebind(`
+		SELECT file_name, file_id, success, failures, errors, skips, total, duration
+		FROM dci_job_results WHERE job_id = ? ORDER BY file_name;`), jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dci_job_results: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close dci_job_results rows: %v", err)
+		}
+	}()
+
+	var results []DciJobResult
+	for rows.Next() {
+		var r DciJobResult
+		if err := rows.Scan(&r.FileName, &r.FileID, &r.Success, &r.Failures, &r.Errors, &r.Skips, &r.Total, &r.Duration); err != nil {
+			return nil, fmt.Errorf("failed to scan dci_job_results row: %w", err)
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,49 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertsuiteJobResults(t *testing.T) {
+	runs := dciRunsFromJSON(t, `{"jobs": [{
+		"id": "job-1",
+		"created_at": "2024-11-26T12:00:00.000000",
+		"components": [{"name": "certsuite v5.2.1"}],
+		"results": [
+			{"name": "certsuite-tests_junit.xml", "file_id": "file-1", "success": 10, "failures": 2, "total": 12, "time": 300},
+			{"name": "preflight_junit.xml", "file_id": "file-2", "success": 4, "skips": 1, "total": 5, "time": 20},
+			{"name": "preflight_junit.xml", "file_id": "file-3", "success": 1, "total": 1, "time": 5},
+			{"name": "logs.txt", "file_id": "file-4"}
+		]
+	}]}`)
+
+	results := certsuiteJobResults(runs, novemberWindow, globs{"*_junit.xml"})
+	assert.Equal(t, map[string][]DciJobResult{"job-1": {
+		{FileName: "certsuite-tests_junit.xml", FileID: "file-1", Success: 10, Failures: 2, Total: 12, Duration: 300},
+		{FileName: "preflight_junit.xml", FileID: "file-2", Success: 5, Skips: 1, Total: 6, Duration: 25},
+	}}, results)
+
+	// The certsuite totals only sum the certsuite result files
+	jobs := certsuiteJobs(runs, novemberWindow, dciOptions{certsuiteFiles: globs{"certsuite-*"}})
+	require.Len(t, jobs, 1)
+	assert.Equal(t, 10, jobs[0].TotalSuccess)
+}
+
+func TestReplaceDciJobResults(t *testing.T) {
+	store := newSQLiteStore(t)
+
+	require.NoError(t, store.ReplaceDciJobResults("job-1", []DciJobResult{
+		{FileName: "a.xml", Success: 1, Total: 1},
+		{FileName: "b.xml", Failures: 2, Total: 2},
+	}))
+	require.NoError(t, store.ReplaceDciJobResults("job-1", []DciJobResult{
+		{FileName: "b.xml", FileID: "file-2", Failures: 1, Total: 1, Duration: 7},
+	}))
+
+	results, err := store.GetDciJobResults("job-1")
+	require.NoError(t, err)
+	assert.Equal(t, []DciJobResult{{FileName: "b.xml", FileID: "file-2", Failures: 1, Total: 1, Duration: 7}}, results)
+}
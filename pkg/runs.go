@@ -0,0 +1,110 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SyncResult summarizes what a fetch wrote to the store.
+// Inserted and Updated count the rows of the source's main table, aggregated_logs or
+// dci_components. Written counts the rows the fetch replaced or upserted in the tables
+// detailing them, such as test cases, tag pulls or tags, which are not told apart.
+type SyncResult struct {
+	Inserted int
+	Updated  int
+	Written  int
+	// Latest is the newest data time ingested, or the zero time if nothing was.
+	Latest time.Time
+}
+
+// record counts a stored row whose data dates from t.
+func (r *SyncResult) record(inserted bool, t time.Time) {
+	if inserted {
+		r.Inserted++
+	} else {
+		r.Updated++
+	}
+	if t.After(r.Latest) {
+		r.Latest = t
+	}
+}
+
+// SyncRun is the audit record of one sync of a source.
+type SyncRun struct {
+	ID           int64
+	Source       string
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	RowsInserted int
+	RowsUpdated  int
+	// RowsWritten counts the rows written to the tables detailing the main one.
+	RowsWritten int
+	// Error is the error the sync failed with, empty if it succeeded.
+	Error string
+	// Version is the certsuite-overview version that ran the sync.
+	Version string
+}
+
+// TrackSyncRun runs fetch and records it in the sync run audit log, whether it succeeds or not.
+// It returns the error of fetch, if any.
+func TrackSyncRun(store Store, source, version string, fetch func() (SyncResult, error)) error {
+	run := SyncRun{Source: source, Version: version, StartedAt: time.Now().UTC()}
+	result, err := fetch()
+	run.FinishedAt = time.Now().UTC()
+	run.RowsInserted, run.RowsUpdated, run.RowsWritten = result.Inserted, result.Updated, result.Written
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	if recordErr := store.RecordSyncRun(run); recordErr != nil {
+		if err != nil {
+			log.Printf("Failed to record %s sync run: %v", source, recordErr)
+			return err
+		}
+		return fmt.Errorf("failed to record %s sync run: %w", source, recordErr)
+	}
+	log.Printf("Recorded %s sync run: %d rows inserted, %d rows updated, %d detail rows written",
+		source, run.RowsInserted, run.RowsUpdated, run.RowsWritten)
+	return err
+}
+
+func (s *sqlStore) RecordSyncRun(run SyncRun) error {
+	query := s.dialect.rebind(`INSERT INTO sync_runs (source, started_at, finished_at, rows_inserted, rows_updated, rows_written, error, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);`)
+	runErr := sql.NullString{String: run.Error, Valid: run.Error != ""}
+	_, err := s.db.Exec(query, run.Source,
+		run.StartedAt.UTC().Format(dbTimestampFormat), run.FinishedAt.UTC().Format(dbTimestampFormat),
+		run.RowsInserted, run.RowsUpdated, run.RowsWritten, runErr, run.Version)
+	return err
+}
+
+func (s *sqlStore) ListSyncRuns(limit int) ([]SyncRun, error) {
+	rows, err := s.db.Query(s.dialect.rebind(`
+		SELECT id, source, started_at, finished_at, rows_inserted, rows_updated, rows_written, error, version
+		FROM sync_runs ORDER BY started_at DESC, id DESC LIMIT ?;`), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync_runs: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close sync_runs rows: %v", err)
+		}
+	}()
+
+	var runs []SyncRun
+	for rows.Next() {
+		var run SyncRun
+		var startedAt, finishedAt dbTime
+		var runErr sql.NullString
+		if err := rows.Scan(&run.ID, &run.Source, &startedAt, &finishedAt, &run.RowsInserted, &run.RowsUpdated, &run.RowsWritten, &runErr, &run.Version); err != nil {
+			return nil, fmt.Errorf("failed to scan sync_runs row: %w", err)
+		}
+		run.StartedAt, run.FinishedAt, run.Error = startedAt.UTC(), finishedAt.UTC(), runErr.String
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
@@ -0,0 +1,42 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackSyncRun(t *testing.T) {
+	store := newSQLiteStore(t)
+
+	require.NoError(t, TrackSyncRun(store, QuaySource, "v1.2.3", func() (SyncResult, error) {
+		return SyncResult{Inserted: 3, Updated: 2, Written: 7}, nil
+	}))
+	fetchErr := errors.New("DCI is down")
+	err := TrackSyncRun(store, DciSource, "v1.2.3", func() (SyncResult, error) {
+		return SyncResult{Inserted: 1}, fetchErr
+	})
+	assert.ErrorIs(t, err, fetchErr)
+
+	runs, err := store.ListSyncRuns(10)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+
+	// Newest first
+	assert.Equal(t, DciSource, runs[0].Source)
+	assert.Equal(t, 1, runs[0].RowsInserted)
+	assert.Equal(t, "DCI is down", runs[0].Error)
+	assert.Equal(t, QuaySource, runs[1].Source)
+	assert.Equal(t, 3, runs[1].RowsInserted)
+	assert.Equal(t, 2, runs[1].RowsUpdated)
+	assert.Equal(t, 7, runs[1].RowsWritten)
+	assert.Empty(t, runs[1].Error)
+	assert.Equal(t, "v1.2.3", runs[1].Version)
+	assert.False(t, runs[1].FinishedAt.Before(runs[1].StartedAt))
+
+	runs, err = store.ListSyncRuns(1)
+	require.NoError(t, err)
+	assert.Len(t, runs, 1)
+}
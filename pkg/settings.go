@@ -0,0 +1,28 @@
+package pkg
+
+import (
+	"database/sql"
+	"errors"
+)
+
+func (s *sqlStore) GetSetting(name string) (string, bool, error) {
+	var value string
+	err := s.db.QueryRow(s.dialect.rebind(`SELECT value FROM sync_settings WHERE name = ?;`), name).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (s *sqlStore) SaveSetting(name, value string) error {
+	query := s.dialect.upsertQuery("sync_settings",
+		[]string{"name", "value"},
+		[]string{"name"},
+		"value = "+s.dialect.excluded("value"),
+	)
+	_, err := s.db.Exec(query, name, value)
+	return err
+}
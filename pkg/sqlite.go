@@ -0,0 +1,47 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+// connectDBSQLite opens the SQLite database file at path, creating the file if needed.
+func connectDBSQLite(path string) (*sql.DB, error) {
+	logrus.Infof("Opening SQLite database %s...", path)
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database %s: %w", path, err)
+	}
+
+	// SQLite only allows a single writer, so serialize access through one connection.
+	db.SetMaxOpenConns(1)
+
+	if err := pingDB(db); err != nil {
+		return nil, fmt.Errorf("failed to connect to SQLite database %s: %w", path, err)
+	}
+
+	if _, err := db.Exec("PRAGMA busy_timeout = 5000;"); err != nil {
+		return nil, fmt.Errorf("failed to configure SQLite database %s: %w", path, err)
+	}
+
+	return db, nil
+}
+
+// initDBSQLite opens the SQLite database file at path and brings its schema up to date.
+func initDBSQLite(path string) (*sql.DB, error) {
+	db, err := connectDBSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := prepareSchema(db, sqliteDialect); err != nil {
+		return nil, fmt.Errorf("failed to prepare schema of SQLite database %s: %w", path, err)
+	}
+
+	logrus.Info("SQLite database initialized successfully.")
+	return db, nil
+}
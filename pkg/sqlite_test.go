@@ -0,0 +1,59 @@
+package pkg
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSQLiteStore returns a Store backed by a fresh SQLite file in a temporary directory.
+func newSQLiteStore(t *testing.T) *sqlStore {
+	t.Helper()
+	db, err := initDBSQLite(filepath.Join(t.TempDir(), "certsuite_usage.db"))
+	require.NoError(t, err)
+	store := &sqlStore{db: db, dialect: sqliteDialect}
+	t.Cleanup(func() { assert.NoError(t, store.Close()) })
+	return store
+}
+
+func TestSQLiteStoreUpserts(t *testing.T) {
+	store := newSQLiteStore(t)
+
+	for _, upsert := range []struct {
+		aggregate QuayAggregate
+		inserted  bool
+	}{
+		{QuayAggregate{Datetime: "2024-11-26", Namespace: "ns", Repository: "certsuite", Count: 10, Kind: "pull_repo"}, true},
+		{QuayAggregate{Datetime: "2024-11-26", Namespace: "ns", Repository: "certsuite", Count: 15, Kind: "pull_repo"}, false},
+		{QuayAggregate{Datetime: "2024-11-26", Namespace: "ns", Repository: "certsuite", Count: 1, Kind: "push_repo"}, true},
+		{QuayAggregate{Datetime: "2024-11-26", Namespace: "ns", Repository: "collector", Count: 3, Kind: "pull_repo"}, true},
+	} {
+		inserted, err := store.UpsertQuayAggregate(upsert.aggregate)
+		require.NoError(t, err)
+		assert.Equal(t, upsert.inserted, inserted)
+	}
+
+	aggregates, err := store.GetQuayAggregates()
+	require.NoError(t, err)
+	assert.Equal(t, []QuayAggregate{
+		{Datetime: "2024-11-26", Namespace: "ns", Repository: "certsuite", Count: 15, Kind: "pull_repo"},
+		{Datetime: "2024-11-26", Namespace: "ns", Repository: "certsuite", Count: 1, Kind: "push_repo"},
+		{Datetime: "2024-11-26", Namespace: "ns", Repository: "collector", Count: 3, Kind: "pull_repo"},
+	}, aggregates)
+
+	job := DciJob{JobID: "job-1", CommitHash: "abc123", CreatedAt: "2024-11-26T12:00:00", TotalSuccess: 10, TotalFailures: 2}
+	inserted, err := store.UpsertDciJob(job)
+	require.NoError(t, err)
+	assert.True(t, inserted)
+	inserted, err = store.UpsertDciJob(job)
+	require.NoError(t, err)
+	assert.False(t, inserted)
+
+	jobs, err := store.GetDciJobs()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, 10, jobs[0].TotalSuccess)
+	assert.Equal(t, 2, jobs[0].TotalFailures)
+}
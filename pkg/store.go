@@ -0,0 +1,116 @@
+package pkg
+
+import "time"
+
+// QuayAggregate is the number of Quay log events of a given kind on one day in one repository.
+type QuayAggregate struct {
+	Datetime   string // Day of the events, formatted as YYYY-MM-DD.
+	Namespace  string
+	Repository string
+	Count      int
+	Kind       string
+}
+
+// DciJob holds the certsuite results reported by a single DCI job.
+type DciJob struct {
+	JobID         string
+	CommitHash    string // Commit SHA of the certsuite build, empty if unknown.
+	CreatedAt     string
+	TotalSuccess  int
+	TotalFailures int
+	TotalErrors   int
+	TotalSkips    int
+	OcpVersion    string // OpenShift version the job ran on, empty if unknown.
+	Topic         string // DCI topic of the job, such as OCP-4.16.
+	RemoteciID    string
+	TeamID        string
+	// Certsuite build the job ran, see CertsuiteComponent.
+	Product          string
+	CertsuiteVersion string
+	IsRelease        bool
+	// Lifecycle of the job, see setJobStatus.
+	State        string // DCI resource state, such as active.
+	Status       string // DCI job status, such as running, success, failure, error or killed.
+	StatusReason string
+	StartedAt    string // Same layout as CreatedAt.
+	EndedAt      string // Empty until the job finishes.
+	Duration     int    // Seconds.
+	Outcome      string // One of JobOutcomeTested, JobOutcomeInfrastructureFailure or JobOutcomeRunning.
+}
+
+// DciTestCase is the outcome of one certsuite test case in a DCI job's JUnit report.
+type DciTestCase struct {
+	JobID    string
+	Suite    string
+	Name     string
+	Status   string // One of TestCasePassed, TestCaseFailed, TestCaseError or TestCaseSkipped.
+	Duration float64
+	Message  string
+}
+
+// Store persists the certsuite usage data collected by the fetchers.
+type Store interface {
+	// AssignLegacyQuayAggregates attributes the Quay aggregates synced before their repository
+	// was recorded to repository, returning the number of rows assigned.
+	AssignLegacyQuayAggregates(repository QuayRepository) (int, error)
+	// UpsertQuayAggregate records the Quay events of one kind on one day in one repository, reporting whether the row is new.
+	UpsertQuayAggregate(aggregate QuayAggregate) (bool, error)
+	// ReplaceQuayTagPulls replaces the pull counts by tag of a repository on the days
+	// from since to until, both inclusive and formatted as YYYY-MM-DD.
+	ReplaceQuayTagPulls(repository QuayRepository, since, until string, tagPulls []QuayTagPull) error
+	// ReplaceQuayPullBreakdown replaces the pull counts by client type and region of a repository
+	// on the days from since to until, both inclusive and formatted as YYYY-MM-DD.
+	ReplaceQuayPullBreakdown(repository QuayRepository, since, until string, breakdown []QuayPullBreakdown) error
+	// ReplaceQuayPullPerformers replaces the pull counts by performer of a repository on the days
+	// from since to until, both inclusive and formatted as YYYY-MM-DD.
+	ReplaceQuayPullPerformers(repository QuayRepository, since, until string, performers []QuayPullPerformer) error
+	// ReplaceQuayTags replaces the tags of a repository in the inventory snapshot of a day, formatted as YYYY-MM-DD.
+	ReplaceQuayTags(day string, repository QuayRepository, tags []QuayTag) error
+	// ReplaceQuayVulnerabilities replaces the vulnerability summaries of a repository's tags on a day, formatted as YYYY-MM-DD.
+	ReplaceQuayVulnerabilities(day string, repository QuayRepository, summaries []QuayVulnerabilitySummary) error
+	// UpsertDciJob records the certsuite results of a DCI job, reporting whether the row is new.
+	UpsertDciJob(job DciJob) (bool, error)
+	// GetQuayAggregates returns every stored Quay aggregate, oldest first.
+	GetQuayAggregates() ([]QuayAggregate, error)
+	// GetDciJobs returns every stored DCI job, oldest first.
+	GetDciJobs() ([]DciJob, error)
+	// GetUnfinishedDciJobs returns the stored DCI jobs that were still running, oldest first.
+	GetUnfinishedDciJobs() ([]DciJob, error)
+	// ReplaceDciTestCases replaces the stored test cases of a DCI job with cases.
+	ReplaceDciTestCases(jobID string, cases []DciTestCase) error
+	// GetDciJobsWithTestCases returns the IDs of the DCI jobs whose test cases are stored.
+	GetDciJobsWithTestCases() (map[string]bool, error)
+	// SavePendingDciFiles records the JUnit reports of a DCI job whose download failed, counting
+	// one more attempt, so its test cases are retried by later syncs.
+	SavePendingDciFiles(jobID string, fileIDs []string) error
+	// GetPendingDciFiles returns the JUnit reports waiting to be retried.
+	GetPendingDciFiles() ([]DciPendingFile, error)
+	// DeletePendingDciFiles forgets the pending reports of a DCI job.
+	DeletePendingDciFiles(jobID string) error
+	// UpsertDciTeam records a team and remoteci that ran certsuite jobs.
+	UpsertDciTeam(team DciTeam) error
+	// ReplaceDciJobComponents replaces the stored components of a DCI job with components.
+	ReplaceDciJobComponents(jobID string, components []DciJobComponent) error
+	// ReplaceDciJobResults replaces the stored result files of a DCI job with results.
+	ReplaceDciJobResults(jobID string, results []DciJobResult) error
+	// GetFlakyTestGroups returns the test cases whose outcome flipped on the same commit and OCP version.
+	GetFlakyTestGroups() ([]FlakyTestGroup, error)
+	// GetBackfillCheckpoint returns how far a backfill of the range has completed, if it was started.
+	GetBackfillCheckpoint(rng Window) (time.Time, bool, error)
+	// SaveBackfillCheckpoint records that a backfill of the range has completed up to completedUntil.
+	SaveBackfillCheckpoint(rng Window, completedUntil time.Time) error
+	// GetWatermark returns the newest data time ingested from the source, if any was.
+	GetWatermark(source string) (time.Time, bool, error)
+	// AdvanceWatermark moves the watermark of the source forward to watermark; it never moves it back.
+	AdvanceWatermark(source string, watermark time.Time) error
+	// GetSetting returns the value of a setting the stored data depends on, if it was recorded.
+	GetSetting(name string) (string, bool, error)
+	// SaveSetting records the value of a setting the stored data depends on.
+	SaveSetting(name, value string) error
+	// RecordSyncRun appends a sync run to the audit log.
+	RecordSyncRun(run SyncRun) error
+	// ListSyncRuns returns the most recent sync runs, newest first.
+	ListSyncRuns(limit int) ([]SyncRun, error)
+	// Close releases the underlying connection.
+	Close() error
+}
@@ -0,0 +1,26 @@
+package pkg
+
+// fakeStore is an in-memory Store used to test the fetchers without a database.
+// Methods that a test does not exercise fall through to the nil embedded Store.
+type fakeStore struct {
+	Store
+	quayAggregates []QuayAggregate
+	dciJobs        []DciJob
+	err            error
+}
+
+func (f *fakeStore) UpsertQuayAggregate(aggregate QuayAggregate) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	f.quayAggregates = append(f.quayAggregates, aggregate)
+	return true, nil
+}
+
+func (f *fakeStore) UpsertDciJob(job DciJob) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	f.dciJobs = append(f.dciJobs, job)
+	return true, nil
+}
@@ -0,0 +1,142 @@
+package pkg
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"time"
+
+	quay "github.com/sebrandon1/go-quay/lib"
+	"github.com/sirupsen/logrus"
+)
+
+// QuayTagPull is the number of pulls of one tag of a Quay repository on one day.
+type QuayTagPull struct {
+	Datetime   string // Day of the pulls, formatted as YYYY-MM-DD.
+	Namespace  string
+	Repository string
+	// Tag is the pulled tag, empty for pulls by manifest digest.
+	Tag   string
+	Count int
+}
+
+// fetchQuayPullLogs returns the pull events of a repository's usage logs between two
+// days, formatted as DateFormat and both included, following every page of the logs.
+func fetchQuayPullLogs(client *quay.Client, repository QuayRepository, startDate, endDate string) ([]quay.LogEntry, error) {
+	path := fmt.Sprintf("/repository/%s/%s/logs", repository.Namespace, repository.Name)
+	query := url.Values{"starttime": {startDate}, "endtime": {endDate}}
+
+	var pulls []quay.LogEntry
+	for page := 1; ; page++ {
+		var logs quay.Logs
+		if err := getQuayAPI(client, path, query, &logs); err != nil {
+			return nil, fmt.Errorf("failed to fetch usage logs of %s: %w", repository, err)
+		}
+		for _, entry := range logs.Logs {
+			if entry.Kind == quayPullKind {
+				pulls = append(pulls, entry)
+			}
+		}
+		if logs.NextPage == "" {
+			log.Printf("Read %d pulls of %s from %d pages of usage logs", len(pulls), repository, page)
+			return pulls, nil
+		}
+		query.Set("next_page", logs.NextPage)
+	}
+}
+
+// quayTagPulls counts the pulls of a repository by day and tag, ordered by day and tag.
+func quayTagPulls(repository QuayRepository, pulls []quay.LogEntry) ([]QuayTagPull, error) {
+	counts := map[QuayTagPull]int{}
+	for _, entry := range pulls {
+		pulledAt, err := time.Parse(quayDatetimeFormat, entry.Datetime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Quay datetime format: %v", entry.Datetime)
+		}
+		key := QuayTagPull{
+			Datetime:   pulledAt.UTC().Format("2006-01-02"),
+			Namespace:  repository.Namespace,
+			Repository: repository.Name,
+			Tag:        entry.Metadata.Tag,
+		}
+		counts[key]++
+	}
+
+	tagPulls := make([]QuayTagPull, 0, len(counts))
+	for key, count := range counts {
+		key.Count = count
+		tagPulls = append(tagPulls, key)
+	}
+	sort.Slice(tagPulls, func(i, j int) bool {
+		if tagPulls[i].Datetime != tagPulls[j].Datetime {
+			return tagPulls[i].Datetime < tagPulls[j].Datetime
+		}
+		return tagPulls[i].Tag < tagPulls[j].Tag
+	})
+	return tagPulls, nil
+}
+
+// storeQuayTagPulls replaces the per-tag pull counts of a repository on the days from since
+// to until, both inclusive, which the pulls were read for.
+func storeQuayTagPulls(store Store, repository QuayRepository, since, until string, tagPulls []QuayTagPull) (int, error) {
+	if err := store.ReplaceQuayTagPulls(repository, since, until, tagPulls); err != nil {
+		return 0, fmt.Errorf("failed to store pulls of %s by tag from %s to %s: %w", repository, since, until, err)
+	}
+	log.Printf("Stored %d daily tag pull counts", len(tagPulls))
+	return len(tagPulls), nil
+}
+
+func (s *sqlStore) ReplaceQuayTagPulls(repository QuayRepository, since, until string, tagPulls []QuayTagPull) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	rollback := func(err error) error {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logrus.Errorf("failed to roll back pulls by tag of %s: %v", repository, rbErr)
+		}
+		return err
+	}
+
+	// The logs hold every pull of each day synced, so tags no longer pulled on those days must not be kept
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM quay_tag_pulls
+		WHERE namespace = ? AND repository = ? AND datetime >= ? AND datetime <= ?;`),
+		repository.Namespace, repository.Name, since, until); err != nil {
+		return rollback(err)
+	}
+	insert := s.dialect.rebind(`INSERT INTO quay_tag_pulls (datetime, namespace, repository, tag, count)
+		VALUES (?, ?, ?, ?, ?);`)
+	for _, p := range tagPulls {
+		if _, err := tx.Exec(insert, p.Datetime, p.Namespace, p.Repository, p.Tag, p.Count); err != nil {
+			return rollback(err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetQuayTagPulls returns every stored tag pull count, ordered by day, repository and tag.
+func (s *sqlStore) GetQuayTagPulls() ([]QuayTagPull, error) {
+	rows, err := s.db.Query(`SELECT datetime, namespace, repository, tag, count FROM quay_tag_pulls
+		ORDER BY datetime, namespace, repository, tag;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quay_tag_pulls: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close quay_tag_pulls rows: %v", err)
+		}
+	}()
+
+	var tagPulls []QuayTagPull
+	for rows.Next() {
+		var p QuayTagPull
+		var datetime dbTime
+		if err := rows.Scan(&datetime, &p.Namespace, &p.Repository, &p.Tag, &p.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan quay_tag_pulls row: %w", err)
+		}
+		p.Datetime = datetime.Format("2006-01-02")
+		tagPulls = append(tagPulls, p)
+	}
+	return tagPulls, rows.Err()
+}
@@ -0,0 +1,100 @@
+package pkg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	quay "github.com/sebrandon1/go-quay/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newQuayTestClient returns a Quay client of a test server answering every path of handlers.
+func newQuayTestClient(t *testing.T, handlers map[string]http.HandlerFunc) *quay.Client {
+	t.Helper()
+	mux := http.NewServeMux()
+	for path, handler := range handlers {
+		mux.HandleFunc(path, handler)
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	saved := quayAPIURL
+	quayAPIURL = server.URL
+	t.Cleanup(func() { quayAPIURL = saved })
+
+	client, err := quay.NewClient("token")
+	require.NoError(t, err)
+	return client
+}
+
+func TestFetchQuayPullLogs(t *testing.T) {
+	client := newQuayTestClient(t, map[string]http.HandlerFunc{
+		"/repository/redhat-best-practices-for-k8s/certsuite/logs": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			assert.Equal(t, "11/26/2024", r.URL.Query().Get("starttime"))
+			assert.Equal(t, "11/27/2024", r.URL.Query().Get("endtime"))
+			if r.URL.Query().Get("next_page") == "" {
+				_, _ = w.Write([]byte(`{"logs": [
+					{"kind": "pull_repo", "datetime": "Tue, 26 Nov 2024 10:00:00 -0000", "metadata": {"tag": "latest"}},
+					{"kind": "push_repo", "datetime": "Tue, 26 Nov 2024 11:00:00 -0000", "metadata": {"tag": "v5.2.1"}}
+				], "next_page": "page-2"}`))
+				return
+			}
+			assert.Equal(t, "page-2", r.URL.Query().Get("next_page"))
+			_, _ = w.Write([]byte(`{"logs": [
+				{"kind": "pull_repo", "datetime": "Tue, 26 Nov 2024 12:00:00 -0000", "metadata": {"tag": "v5.2.1"}},
+				{"kind": "pull_repo", "datetime": "Tue, 26 Nov 2024 13:00:00 -0000", "metadata": {"tag": "latest"}},
+				{"kind": "pull_repo", "datetime": "Wed, 27 Nov 2024 09:00:00 -0000", "metadata": {"manifest_digest": "sha256:0a1b"}}
+			]}`))
+		},
+	})
+
+	pulls, err := fetchQuayPullLogs(client, certsuiteRepository, "11/26/2024", "11/27/2024")
+	require.NoError(t, err)
+	assert.Len(t, pulls, 4)
+
+	tagPulls, err := quayTagPulls(certsuiteRepository, pulls)
+	require.NoError(t, err)
+	assert.Equal(t, []QuayTagPull{
+		{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Tag: "latest", Count: 2},
+		{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Tag: "v5.2.1", Count: 1},
+		{Datetime: "2024-11-27", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Tag: "", Count: 1},
+	}, tagPulls)
+}
+
+func TestFetchQuayPullLogsError(t *testing.T) {
+	client := newQuayTestClient(t, map[string]http.HandlerFunc{
+		"/repository/redhat-best-practices-for-k8s/certsuite/logs": func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "denied", http.StatusForbidden)
+		},
+	})
+
+	_, err := fetchQuayPullLogs(client, certsuiteRepository, "11/26/2024", "11/27/2024")
+	assert.ErrorContains(t, err, "403")
+}
+
+func TestStoreQuayTagPullsReplacesSyncedDays(t *testing.T) {
+	store := newSQLiteStore(t)
+	earlier := QuayTagPull{Datetime: "2024-11-25", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Tag: "v5.2.0", Count: 3}
+	_, err := storeQuayTagPulls(store, certsuiteRepository, "2024-11-25", "2024-11-25", []QuayTagPull{earlier})
+	require.NoError(t, err)
+
+	tagPulls := []QuayTagPull{
+		{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Tag: "latest", Count: 2},
+		{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Tag: "v5.2.1", Count: 1},
+	}
+	written, err := storeQuayTagPulls(store, certsuiteRepository, "2024-11-26", "2024-11-27", tagPulls)
+	require.NoError(t, err)
+	assert.Equal(t, 2, written)
+
+	// A re-sync of the same days drops the tags it no longer reads pulls of
+	resynced := []QuayTagPull{{Datetime: "2024-11-26", Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Tag: "latest", Count: 5}}
+	_, err = storeQuayTagPulls(store, certsuiteRepository, "2024-11-26", "2024-11-27", resynced)
+	require.NoError(t, err)
+
+	stored, err := store.GetQuayTagPulls()
+	require.NoError(t, err)
+	assert.Equal(t, append([]QuayTagPull{earlier}, resynced...), stored)
+}
@@ -0,0 +1,132 @@
+package pkg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+
+	dci "github.com/sebrandon1/go-dci/lib"
+)
+
+// DciTeam is the team and remoteci that ran certsuite DCI jobs.
+type DciTeam struct {
+	TeamID       string
+	RemoteciID   string
+	TeamName     string
+	RemoteciName string
+	// External is true for partner teams, false for Red Hat teams.
+	External bool
+	// LastSeen is the creation time of the team's latest job, in the DCI time format.
+	LastSeen string
+}
+
+// anonymizer replaces partner identifiers and names with salted hashes, so that
+// distinct partners can still be counted without exposing who they are.
+// The zero anonymizer leaves them untouched.
+type anonymizer struct {
+	salt []byte
+}
+
+// newAnonymizer returns an anonymizer hashing with salt, or one leaving values untouched if salt is empty.
+func newAnonymizer(salt string) anonymizer {
+	return anonymizer{salt: []byte(salt)}
+}
+
+// anonymize returns the salted hash of value, or value itself when anonymization is off.
+// Empty values stay empty so that missing identifiers remain recognizable.
+func (a anonymizer) anonymize(value string) string {
+	if len(a.salt) == 0 || value == "" {
+		return value
+	}
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// anonymizationSetting is the sync setting recording how the stored DCI data is anonymized.
+const anonymizationSetting = "anonymization"
+
+// fingerprint identifies the salt without revealing it, or is "off" when anonymization is off.
+func (a anonymizer) fingerprint() string {
+	if len(a.salt) == 0 {
+		return "off"
+	}
+	return a.anonymize(anonymizationSetting)
+}
+
+// checkAnonymization refuses to store DCI data anonymized differently from the data already
+// stored, which would leave raw names next to hashes and count every partner twice.
+// The first check records the anonymization of the store.
+func checkAnonymization(store Store, anon anonymizer) error {
+	stored, found, err := store.GetSetting(anonymizationSetting)
+	if err != nil {
+		return fmt.Errorf("failed to read the anonymization of the stored DCI data: %w", err)
+	}
+	if !found {
+		if err := store.SaveSetting(anonymizationSetting, anon.fingerprint()); err != nil {
+			return fmt.Errorf("failed to record the anonymization of the stored DCI data: %w", err)
+		}
+		return nil
+	}
+	if stored != anon.fingerprint() {
+		return fmt.Errorf("ANONYMIZE_SALT differs from the setting the stored DCI data was synced with; it cannot be changed once data is stored")
+	}
+	return nil
+}
+
+// certsuiteTeams lists the teams and remotecis of the certsuite DCI jobs created in the window.
+func certsuiteTeams(runs []dci.JobsResponse, window Window, anon anonymizer) []DciTeam {
+	var teams []DciTeam
+	index := map[[2]string]int{}
+	forEachCertsuiteJob(runs, window, func(job dci.Job, _ CertsuiteComponent) {
+		if job.TeamID == "" {
+			return
+		}
+		key := [2]string{job.TeamID, job.RemoteciID}
+		if i, ok := index[key]; ok {
+			if job.CreatedAt > teams[i].LastSeen {
+				teams[i].LastSeen = job.CreatedAt
+			}
+			return
+		}
+		index[key] = len(teams)
+		teams = append(teams, DciTeam{
+			TeamID:       anon.anonymize(job.TeamID),
+			RemoteciID:   anon.anonymize(job.RemoteciID),
+			TeamName:     anon.anonymize(job.Team.Name),
+			RemoteciName: anon.anonymize(job.Remoteci.Name),
+			External:     job.Team.External,
+			LastSeen:     job.CreatedAt,
+		})
+	})
+	return teams
+}
+
+// storeDciTeams saves the teams in the store.
+func storeDciTeams(store Store, teams []DciTeam) (int, error) {
+	stored := 0
+	for _, team := range teams {
+		if err := store.UpsertDciTeam(team); err != nil {
+			return stored, fmt.Errorf("failed to insert DCI team %s: %w", team.TeamID, err)
+		}
+		stored++
+	}
+	log.Printf("Stored %d DCI teams and remotecis", len(teams))
+	return stored, nil
+}
+
+func (s *sqlStore) UpsertDciTeam(team DciTeam) error {
+	lastSeen := s.dialect.excluded("last_seen")
+	query := s.dialect.upsertQuery("dci_teams",
+		[]string{"team_id", "remoteci_id", "team_name", "remoteci_name", "external", "last_seen"},
+		[]string{"team_id", "remoteci_id"},
+		"team_name = "+s.dialect.excluded("team_name"),
+		"remoteci_name = "+s.dialect.excluded("remoteci_name"),
+		"external = "+s.dialect.excluded("external"),
+		fmt.Sprintf("last_seen = CASE WHEN dci_teams.last_seen > %s THEN dci_teams.last_seen ELSE %s END", lastSeen, lastSeen),
+	)
+	_, err := s.db.Exec(query, team.TeamID, team.RemoteciID, team.TeamName, team.RemoteciName, team.External, team.LastSeen)
+	return err
+}
@@ -0,0 +1,109 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymizer(t *testing.T) {
+	assert.Equal(t, "team-1", anonymizer{}.anonymize("team-1"))
+
+	anon := newAnonymizer("s3cret")
+	hashed := anon.anonymize("team-1")
+	assert.Len(t, hashed, 32)
+	assert.NotContains(t, hashed, "team")
+	assert.Equal(t, hashed, anon.anonymize("team-1"))
+	assert.NotEqual(t, hashed, anon.anonymize("team-2"))
+	assert.NotEqual(t, hashed, newAnonymizer("other").anonymize("team-1"))
+	assert.Empty(t, anon.anonymize(""))
+}
+
+func TestCheckAnonymization(t *testing.T) {
+	store := newSQLiteStore(t)
+	anon := newAnonymizer("s3cret")
+
+	// The first sync records the anonymization, which later syncs must keep
+	require.NoError(t, checkAnonymization(store, anon))
+	require.NoError(t, checkAnonymization(store, anon))
+	fingerprint, found, err := store.GetSetting(anonymizationSetting)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.NotContains(t, fingerprint, "s3cret")
+
+	assert.ErrorContains(t, checkAnonymization(store, anonymizer{}), "ANONYMIZE_SALT")
+	assert.ErrorContains(t, checkAnonymization(store, newAnonymizer("other")), "ANONYMIZE_SALT")
+
+	// Turning anonymization on after syncing raw names is refused too
+	raw := newSQLiteStore(t)
+	require.NoError(t, checkAnonymization(raw, anonymizer{}))
+	assert.Error(t, checkAnonymization(raw, anon))
+}
+
+func TestStoreDciTeams(t *testing.T) {
+	runs := dciRunsFromJSON(t, `{"jobs": [
+		{
+			"id": "job-1",
+			"created_at": "2024-11-20T12:00:00.000000",
+			"team_id": "team-1",
+			"team": {"name": "Partner One", "external": true},
+			"remoteci_id": "remoteci-1",
+			"remoteci": {"name": "lab-1"},
+			"components": [{"name": "certsuite v5.2.1"}]
+		},
+		{
+			"id": "job-2",
+			"created_at": "2024-11-26T12:00:00.000000",
+			"team_id": "team-1",
+			"team": {"name": "Partner One", "external": true},
+			"remoteci_id": "remoteci-1",
+			"remoteci": {"name": "lab-1"},
+			"components": [{"name": "certsuite v5.2.1"}]
+		},
+		{
+			"id": "job-3",
+			"created_at": "2024-11-21T12:00:00.000000",
+			"team_id": "team-2",
+			"team": {"name": "Red Hat CI", "external": false},
+			"remoteci_id": "remoteci-2",
+			"components": [{"name": "certsuite v5.2.1"}]
+		}
+	]}`)
+	anon := newAnonymizer("s3cret")
+
+	teams := certsuiteTeams(runs, novemberWindow, anon)
+
+	require.Len(t, teams, 2)
+	assert.Equal(t, DciTeam{
+		TeamID:       anon.anonymize("team-1"),
+		RemoteciID:   anon.anonymize("remoteci-1"),
+		TeamName:     anon.anonymize("Partner One"),
+		RemoteciName: anon.anonymize("lab-1"),
+		External:     true,
+		LastSeen:     "2024-11-26T12:00:00.000000",
+	}, teams[0])
+	assert.False(t, teams[1].External)
+
+	// The jobs reference the same hashed identifiers as the team dimension
+	jobs := certsuiteJobs(runs, novemberWindow, dciOptions{anon: anon, certsuiteFiles: testDciOptions.certsuiteFiles})
+	assert.Equal(t, teams[0].TeamID, jobs[0].TeamID)
+	assert.Equal(t, teams[0].RemoteciID, jobs[0].RemoteciID)
+
+	store := newSQLiteStore(t)
+	written, err := storeDciTeams(store, teams)
+	require.NoError(t, err)
+	assert.Equal(t, len(teams), written)
+	// An older sync does not move last_seen back
+	older := teams[0]
+	older.LastSeen = "2024-11-20T12:00:00.000000"
+	_, err = storeDciTeams(store, []DciTeam{older})
+	require.NoError(t, err)
+
+	var partners int
+	require.NoError(t, store.db.QueryRow(`SELECT COUNT(DISTINCT team_id) FROM dci_teams WHERE external;`).Scan(&partners))
+	assert.Equal(t, 1, partners)
+	var lastSeen dbTime
+	require.NoError(t, store.db.QueryRow(`SELECT last_seen FROM dci_teams WHERE team_id = ?;`, teams[0].TeamID).Scan(&lastSeen))
+	assert.Equal(t, "2024-11-26", lastSeen.Format("2006-01-02"))
+}
@@ -0,0 +1,288 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+
+	dci "github.com/sebrandon1/go-dci/lib"
+	"github.com/sirupsen/logrus"
+)
+
+// The region and service DCI expects in its AWS-style request signatures.
+const (
+	dciRegion  = "BHS3"
+	dciService = "api"
+)
+
+// dciResultFile is a result file attached to a DCI job.
+type dciResultFile struct {
+	JobID  string
+	FileID string
+}
+
+// certsuiteJUnitFiles lists the certsuite JUnit reports, selected by certsuiteFiles,
+// of the DCI jobs created in the window.
+func certsuiteJUnitFiles(runs []dci.JobsResponse, window Window, certsuiteFiles globs) []dciResultFile {
+	var files []dciResultFile
+	forEachCertsuiteJob(runs, window, func(job dci.Job, _ CertsuiteComponent) {
+		for _, result := range job.Results {
+			if certsuiteFiles.match(result.Name) && result.FileID != "" {
+				files = append(files, dciResultFile{JobID: job.ID, FileID: result.FileID})
+			}
+		}
+	})
+	return files
+}
+
+// downloadDciFile returns the content of a DCI file.
+func downloadDciFile(client *dci.Client, fileID string) ([]byte, error) {
+	resp, err := dci.HttpGetWithAWSAuth(client.BaseURL+"/files/"+fileID+"/content", dciRegion, dciService,
+		client.AccessKey, client.SecretKey, 1, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download DCI file %s: %w", fileID, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Failed to close DCI file %s: %v", fileID, err)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download DCI file %s: unexpected status %s", fileID, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// maxDciFileAttempts is how many syncs try to download the JUnit reports of a job before giving up.
+const maxDciFileAttempts = 5
+
+// The widths of the dci_test_cases columns. A MySQL TEXT column holds 64 KiB, which fits
+// maxTestCaseMessageLength characters of up to four bytes.
+const (
+	maxTestCaseNameLength    = 512
+	maxTestCaseSuiteLength   = 255
+	maxTestCaseMessageLength = 16383
+)
+
+// DciPendingFile is a JUnit report of a DCI job whose download failed, to be retried.
+type DciPendingFile struct {
+	JobID    string
+	FileID   string
+	Attempts int
+}
+
+// storeDciTestCases downloads and parses the JUnit reports of every file, along with the reports
+// left pending by earlier syncs, and replaces the test cases of their jobs in the store.
+// Jobs whose test cases are already stored are skipped, as their reports do not change.
+// A job whose reports cannot all be downloaded keeps its stored test cases and is recorded
+// as pending, so later syncs retry it even once it falls outside their window. Reports that
+// cannot be parsed are skipped, as retrying them would not help.
+// It returns the number of test cases stored.
+func storeDciTestCases(store Store, files []dciResultFile, download func(fileID string) ([]byte, error)) (int, error) {
+	pending, err := store.GetPendingDciFiles()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read pending DCI files: %w", err)
+	}
+	withTestCases, err := store.GetDciJobsWithTestCases()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read DCI jobs with test cases: %w", err)
+	}
+	files = slices.DeleteFunc(slices.Clone(files), func(file dciResultFile) bool {
+		return withTestCases[file.JobID]
+	})
+	attempts := map[string]int{}
+	for _, file := range pending {
+		files = append(files, dciResultFile{JobID: file.JobID, FileID: file.FileID})
+		attempts[file.JobID] = max(attempts[file.JobID], file.Attempts)
+	}
+
+	// A job may attach several reports, which all replace the job's test cases at once
+	var jobIDs []string
+	filesByJob := map[string][]string{}
+	for _, file := range files {
+		if _, seen := filesByJob[file.JobID]; !seen {
+			jobIDs = append(jobIDs, file.JobID)
+		}
+		if !slices.Contains(filesByJob[file.JobID], file.FileID) {
+			filesByJob[file.JobID] = append(filesByJob[file.JobID], file.FileID)
+		}
+	}
+
+	stored := 0
+	for _, jobID := range jobIDs {
+		cases, err := downloadDciTestCases(jobID, filesByJob[jobID], download)
+		if err != nil {
+			if err := retryDciTestCases(store, jobID, filesByJob[jobID], attempts[jobID], err); err != nil {
+				return stored, err
+			}
+			continue
+		}
+		if cases != nil {
+			cases = uniqueTestCases(fitTestCases(cases))
+			if err := store.ReplaceDciTestCases(jobID, cases); err != nil {
+				return stored, fmt.Errorf("failed to store test cases of DCI job %s: %w", jobID, err)
+			}
+			stored += len(cases)
+		}
+		if attempts[jobID] > 0 {
+			if err := store.DeletePendingDciFiles(jobID); err != nil {
+				return stored, fmt.Errorf("failed to clear pending files of DCI job %s: %w", jobID, err)
+			}
+		}
+	}
+	log.Printf("Stored %d certsuite test cases from %d DCI jobs", stored, len(jobIDs))
+	return stored, nil
+}
+
+// downloadDciTestCases downloads and parses the JUnit reports of a job. It returns nil cases
+// if none of the reports parse, and an error if any of them cannot be downloaded.
+func downloadDciTestCases(jobID string, fileIDs []string, download func(fileID string) ([]byte, error)) ([]DciTestCase, error) {
+	var cases []DciTestCase
+	for _, fileID := range fileIDs {
+		report, err := download(fileID)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := parseJUnit(jobID, report)
+		if err != nil {
+			log.Printf("Skipping JUnit report %s of DCI job %s: %v", fileID, jobID, err)
+			continue
+		}
+		cases = append(cases, parsed...)
+	}
+	return cases, nil
+}
+
+// retryDciTestCases records the reports of a job that failed to download so a later sync
+// retries them, giving up after maxDciFileAttempts attempts.
+func retryDciTestCases(store Store, jobID string, fileIDs []string, attempts int, downloadErr error) error {
+	if attempts+1 >= maxDciFileAttempts {
+		log.Printf("Giving up on the test cases of DCI job %s after %d attempts: %v", jobID, attempts+1, downloadErr)
+		if err := store.DeletePendingDciFiles(jobID); err != nil {
+			return fmt.Errorf("failed to clear pending files of DCI job %s: %w", jobID, err)
+		}
+		return nil
+	}
+	log.Printf("Retrying the test cases of DCI job %s on the next sync: %v", jobID, downloadErr)
+	if err := store.SavePendingDciFiles(jobID, fileIDs); err != nil {
+		return fmt.Errorf("failed to record pending files of DCI job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+// fitTestCases truncates the fields of the test cases to the width of their columns.
+func fitTestCases(cases []DciTestCase) []DciTestCase {
+	for i := range cases {
+		cases[i].Name = truncate(cases[i].Name, maxTestCaseNameLength)
+		cases[i].Suite = truncate(cases[i].Suite, maxTestCaseSuiteLength)
+		cases[i].Message = truncate(cases[i].Message, maxTestCaseMessageLength)
+	}
+	return cases
+}
+
+// uniqueTestCases drops repeated test case names, keeping the first outcome reported.
+func uniqueTestCases(cases []DciTestCase) []DciTestCase {
+	seen := make(map[string]bool, len(cases))
+	unique := cases[:0]
+	for _, tc := range cases {
+		if seen[tc.Name] {
+			log.Printf("Ignoring repeated test case %q of DCI job %s", tc.Name, tc.JobID)
+			continue
+		}
+		seen[tc.Name] = true
+		unique = append(unique, tc)
+	}
+	return unique
+}
+
+func (s *sqlStore) ReplaceDciTestCases(jobID string, cases []DciTestCase) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	rollback := func(err error) error {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logrus.Errorf("failed to roll back test cases of DCI job %s: %v", jobID, rbErr)
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM dci_test_cases WHERE job_id = ?;`), jobID); err != nil {
+		return rollback(err)
+	}
+	insert := s.dialect.rebind(`INSERT INTO dci_test_cases (job_id, name, suite, status, duration_seconds, message)
+		VALUES (?, ?, ?, ?, ?, ?);`)
+	for _, tc := range cases {
+		if _, err := tx.Exec(insert, jobID, tc.Name, tc.Suite, tc.Status, tc.Duration, tc.Message); err != nil {
+			return rollback(err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetDciJobsWithTestCases returns the IDs of the DCI jobs whose test cases are stored.
+func (s *sqlStore) GetDciJobsWithTestCases() (map[string]bool, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT job_id FROM dci_test_cases;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dci_test_cases: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close dci_test_cases rows: %v", err)
+		}
+	}()
+
+	jobIDs := map[string]bool{}
+	for rows.Next() {
+		var jobID string
+		if err := rows.Scan(&jobID); err != nil {
+			return nil, fmt.Errorf("failed to scan dci_test_cases row: %w", err)
+		}
+		jobIDs[jobID] = true
+	}
+	return jobIDs, rows.Err()
+}
+
+func (s *sqlStore) SavePendingDciFiles(jobID string, fileIDs []string) error {
+	query := s.dialect.upsertQuery("dci_pending_files",
+		[]string{"job_id", "file_id", "attempts"},
+		[]string{"job_id", "file_id"},
+		"attempts = dci_pending_files.attempts + 1",
+	)
+	for _, fileID := range fileIDs {
+		if _, err := s.db.Exec(query, jobID, fileID, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPendingDciFiles returns every pending JUnit report, ordered by job and file.
+func (s *sqlStore) GetPendingDciFiles() ([]DciPendingFile, error) {
+	rows, err := s.db.Query(`SELECT job_id, file_id, attempts FROM dci_pending_files ORDER BY job_id, file_id;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dci_pending_files: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close dci_pending_files rows: %v", err)
+		}
+	}()
+
+	var files []DciPendingFile
+	for rows.Next() {
+		var file DciPendingFile
+		if err := rows.Scan(&file.JobID, &file.FileID, &file.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan dci_pending_files row: %w", err)
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
+
+func (s *sqlStore) DeletePendingDciFiles(jobID string) error {
+	_, err := s.db.Exec(s.dialect.rebind(`DELETE FROM dci_pending_files WHERE job_id = ?;`), jobID)
+	return err
+}
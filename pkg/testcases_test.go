@@ -0,0 +1,129 @@
+package pkg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreDciTestCases(t *testing.T) {
+	store := newSQLiteStore(t)
+	reports := map[string]string{
+		"file-1": `<testsuites><testsuite name="certsuite">
+			<testcase name="access-control-ssh-daemons" time="1"/>
+			<testcase name="networking-ocp-reserved-ports-usage" time="2"><failure message="port 22623 in use"/></testcase>
+		</testsuite></testsuites>`,
+		"file-2": `<testsuites><testsuite name="certsuite">
+			<testcase name="access-control-ssh-daemons" time="1"><failure message="sshd running"/></testcase>
+		</testsuite></testsuites>`,
+		"file-3": `not xml`,
+	}
+	var downloaded []string
+	download := func(fileID string) ([]byte, error) {
+		downloaded = append(downloaded, fileID)
+		report, ok := reports[fileID]
+		if !ok {
+			return nil, errors.New("file not found")
+		}
+		return []byte(report), nil
+	}
+	files := []dciResultFile{
+		{JobID: "job-1", FileID: "file-1"},
+		{JobID: "job-2", FileID: "file-2"},
+		{JobID: "job-3", FileID: "file-3"},
+		{JobID: "job-4", FileID: "missing"},
+	}
+
+	stored, err := storeDciTestCases(store, files, download)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stored)
+
+	// Re-running the sync only downloads the reports of the jobs without stored test cases
+	downloaded = nil
+	stored, err = storeDciTestCases(store, files, download)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored)
+	assert.Equal(t, []string{"file-3", "missing"}, downloaded)
+
+	var total, failed int
+	require.NoError(t, store.db.QueryRow(`SELECT COUNT(*) FROM dci_test_cases;`).Scan(&total))
+	require.NoError(t, store.db.QueryRow(`SELECT COUNT(*) FROM dci_test_cases WHERE status = ?;`, TestCaseFailed).Scan(&failed))
+	assert.Equal(t, 3, total)
+	assert.Equal(t, 2, failed)
+
+	var message string
+	require.NoError(t, store.db.QueryRow(`SELECT message FROM dci_test_cases WHERE job_id = ? AND name = ?;`,
+		"job-1", "networking-ocp-reserved-ports-usage").Scan(&message))
+	assert.Equal(t, "port 22623 in use", message)
+}
+
+func TestStoreDciTestCasesRetriesFailedDownloads(t *testing.T) {
+	store := newSQLiteStore(t)
+	report := `<testsuites><testsuite name="certsuite"><testcase name="access-control-ssh-daemons" time="1"/></testsuite></testsuites>`
+	available := false
+	download := func(fileID string) ([]byte, error) {
+		if !available {
+			return nil, errors.New("DCI is down")
+		}
+		return []byte(report), nil
+	}
+
+	stored, err := storeDciTestCases(store, []dciResultFile{{JobID: "job-1", FileID: "file-1"}}, download)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored)
+	pending, err := store.GetPendingDciFiles()
+	require.NoError(t, err)
+	assert.Equal(t, []DciPendingFile{{JobID: "job-1", FileID: "file-1", Attempts: 1}}, pending)
+
+	// A later sync retries the report even though the job is no longer in its window
+	available = true
+	stored, err = storeDciTestCases(store, nil, download)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored)
+	pending, err = store.GetPendingDciFiles()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestStoreDciTestCasesGivesUp(t *testing.T) {
+	store := newSQLiteStore(t)
+	download := func(string) ([]byte, error) { return nil, errors.New("file not found") }
+
+	_, err := storeDciTestCases(store, []dciResultFile{{JobID: "job-1", FileID: "file-1"}}, download)
+	require.NoError(t, err)
+	pending, err := store.GetPendingDciFiles()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	for attempt := 1; attempt < maxDciFileAttempts; attempt++ {
+		_, err := storeDciTestCases(store, nil, download)
+		require.NoError(t, err)
+	}
+
+	pending, err = store.GetPendingDciFiles()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestStoreDciTestCasesTruncatesFields(t *testing.T) {
+	store := newSQLiteStore(t)
+	name, message := strings.Repeat("n", 600), strings.Repeat("m", 70000)
+	report := `<testsuites><testsuite name="` + strings.Repeat("s", 300) + `">
+		<testcase name="` + name + `"><failure message="` + message + `"/></testcase>
+		<testcase name="` + name + `x"/>
+	</testsuite></testsuites>`
+	download := func(string) ([]byte, error) { return []byte(report), nil }
+
+	// Both names are the same once truncated, so the first outcome is kept
+	stored, err := storeDciTestCases(store, []dciResultFile{{JobID: "job-1", FileID: "file-1"}}, download)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored)
+
+	var storedName, suite, storedMessage string
+	require.NoError(t, store.db.QueryRow(`SELECT name, suite, message FROM dci_test_cases;`).Scan(&storedName, &suite, &storedMessage))
+	assert.Equal(t, name[:maxTestCaseNameLength], storedName)
+	assert.Len(t, suite, maxTestCaseSuiteLength)
+	assert.Len(t, storedMessage, maxTestCaseMessageLength)
+}
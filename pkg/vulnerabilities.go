@@ -0,0 +1,170 @@
+package pkg
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	quay "github.com/sebrandon1/go-quay/lib"
+	"github.com/sirupsen/logrus"
+)
+
+// quayScanned is the status of a manifest whose security scan completed.
+const quayScanned = "scanned"
+
+// quayScanUnavailable is the status recorded for a tag whose security scan could not be fetched.
+const quayScanUnavailable = "unavailable"
+
+// QuayVulnerabilitySummary counts the vulnerabilities found by Quay's security scan of a tag.
+type QuayVulnerabilitySummary struct {
+	Namespace      string
+	Repository     string
+	Tag            string
+	ManifestDigest string
+	// Status is the scan status reported by Quay, such as scanned, queued or unsupported,
+	// or unavailable when the scan could not be fetched. The counts are only meaningful
+	// once it is scanned.
+	Status     string
+	Critical   int
+	High       int
+	Medium     int
+	Low        int
+	Negligible int
+	Unknown    int
+	// Fixable counts the vulnerabilities with a fixed version available.
+	Fixable int
+}
+
+// Total returns the number of vulnerabilities of every severity.
+func (s QuayVulnerabilitySummary) Total() int {
+	return s.Critical + s.High + s.Medium + s.Low + s.Negligible + s.Unknown
+}
+
+// quaySecurityReport is the response of the manifest security endpoint.
+type quaySecurityReport struct {
+	Status string `json:"status"`
+	Data   *struct {
+		Layer struct {
+			Features []struct {
+				Name            string `json:"Name"`
+				Vulnerabilities []struct {
+					Name     string `json:"Name"`
+					Severity string `json:"Severity"`
+					FixedBy  string `json:"FixedBy"`
+				} `json:"Vulnerabilities"`
+			} `json:"Features"`
+		} `json:"Layer"`
+	} `json:"data"`
+}
+
+// fetchQuayVulnerabilities summarizes the security scans of the tags matching scanTags.
+// Tags sharing a manifest are summarized from a single scan. A scan that cannot be fetched
+// is logged and recorded with the unavailable status, so it does not hold the sync back.
+func fetchQuayVulnerabilities(client *quay.Client, repository QuayRepository, tags []QuayTag, scanTags globs) []QuayVulnerabilitySummary {
+	scans := map[string]QuayVulnerabilitySummary{}
+	var summaries []QuayVulnerabilitySummary
+	for _, tag := range tags {
+		if !scanTags.match(tag.Name) || tag.ManifestDigest == "" {
+			continue
+		}
+		scan, ok := scans[tag.ManifestDigest]
+		if !ok {
+			var report quaySecurityReport
+			path := fmt.Sprintf("/repository/%s/%s/manifest/%s/security", repository.Namespace, repository.Name, tag.ManifestDigest)
+			if err := getQuayAPI(client, path, url.Values{"vulnerabilities": {"true"}}, &report); err != nil {
+				log.Printf("Failed to fetch security scan of %s:%s: %v", repository, tag.Name, err)
+				report = quaySecurityReport{Status: quayScanUnavailable}
+			}
+			scan = summarizeQuayScan(report)
+			scans[tag.ManifestDigest] = scan
+		}
+		scan.Namespace, scan.Repository, scan.Tag, scan.ManifestDigest = repository.Namespace, repository.Name, tag.Name, tag.ManifestDigest
+		summaries = append(summaries, scan)
+	}
+	log.Printf("Read the security scans of %d tags of %s", len(summaries), repository)
+	return summaries
+}
+
+// summarizeQuayScan counts the vulnerabilities of a security report by severity.
+func summarizeQuayScan(report quaySecurityReport) QuayVulnerabilitySummary {
+	summary := QuayVulnerabilitySummary{Status: report.Status}
+	if report.Data == nil {
+		return summary
+	}
+	for _, feature := range report.Data.Layer.Features {
+		for _, vulnerability := range feature.Vulnerabilities {
+			switch strings.ToLower(vulnerability.Severity) {
+			case "critical", "defcon1":
+				summary.Critical++
+			case "high":
+				summary.High++
+			case "medium":
+				summary.Medium++
+			case "low":
+				summary.Low++
+			case "negligible":
+				summary.Negligible++
+			default:
+				summary.Unknown++
+			}
+			if vulnerability.FixedBy != "" {
+				summary.Fixable++
+			}
+		}
+	}
+	return summary
+}
+
+func (s *sqlStore) ReplaceQuayVulnerabilities(day string, repository QuayRepository, summaries []QuayVulnerabilitySummary) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	rollback := func(err error) error {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logrus.Errorf("failed to roll back vulnerabilities of %s: %v", repository, rbErr)
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM quay_vulnerabilities WHERE snapshot_date = ? AND namespace = ? AND repository = ?;`),
+		day, repository.Namespace, repository.Name); err != nil {
+		return rollback(err)
+	}
+	insert := s.dialect.rebind(`INSERT INTO quay_vulnerabilities (snapshot_date, namespace, repository, tag, manifest_digest, status,
+		critical, high, medium, low, negligible, unknown, fixable, total)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`)
+	for _, v := range summaries {
+		if _, err := tx.Exec(insert, day, v.Namespace, v.Repository, v.Tag, v.ManifestDigest, v.Status,
+			v.Critical, v.High, v.Medium, v.Low, v.Negligible, v.Unknown, v.Fixable, v.Total()); err != nil {
+			return rollback(err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetQuayVulnerabilities returns the vulnerability summaries of a repository on a day, ordered by tag.
+func (s *sqlStore) GetQuayVulnerabilities(day string, repository QuayRepository) ([]QuayVulnerabilitySummary, error) {
+	rows, err := s.db.Query(s.dialect.rebind(`
+		SELECT tag, manifest_digest, status, critical, high, medium, low, negligible, unknown, fixable FROM quay_vulnerabilities
+		WHERE snapshot_date = ? AND namespace = ? AND repository = ? ORDER BY tag;`), day, repository.Namespace, repository.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quay_vulnerabilities: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Errorf("failed to close quay_vulnerabilities rows: %v", err)
+		}
+	}()
+
+	var summaries []QuayVulnerabilitySummary
+	for rows.Next() {
+		v := QuayVulnerabilitySummary{Namespace: repository.Namespace, Repository: repository.Name}
+		if err := rows.Scan(&v.Tag, &v.ManifestDigest, &v.Status, &v.Critical, &v.High, &v.Medium, &v.Low, &v.Negligible, &v.Unknown, &v.Fixable); err != nil {
+			return nil, fmt.Errorf("failed to scan quay_vulnerabilities row: %w", err)
+		}
+		summaries = append(summaries, v)
+	}
+	return summaries, rows.Err()
+}
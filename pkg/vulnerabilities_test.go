@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchQuayVulnerabilities(t *testing.T) {
+	scans := 0
+	client := newQuayTestClient(t, map[string]http.HandlerFunc{
+		"/repository/redhat-best-practices-for-k8s/certsuite/manifest/sha256:0a1b/security": func(w http.ResponseWriter, r *http.Request) {
+			scans++
+			assert.Equal(t, "true", r.URL.Query().Get("vulnerabilities"))
+			_, _ = w.Write([]byte(`{"status": "scanned", "data": {"Layer": {"Features": [
+				{"Name": "openssl", "Vulnerabilities": [
+					{"Name": "CVE-2024-0001", "Severity": "Critical", "FixedBy": "3.0.9"},
+					{"Name": "CVE-2024-0002", "Severity": "High"}
+				]},
+				{"Name": "glibc", "Vulnerabilities": [
+					{"Name": "CVE-2024-0003", "Severity": "Medium", "FixedBy": "2.34-100"},
+					{"Name": "CVE-2024-0004", "Severity": "Low"},
+					{"Name": "CVE-2024-0005", "Severity": "Unknown"}
+				]},
+				{"Name": "bash"}
+			]}}}`))
+		},
+		"/repository/redhat-best-practices-for-k8s/certsuite/manifest/sha256:2c3d/security": func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"status": "queued", "data": null}`))
+		},
+		"/repository/redhat-best-practices-for-k8s/certsuite/manifest/sha256:6a7b/security": func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "scanner unavailable", http.StatusServiceUnavailable)
+		},
+	})
+	tags := []QuayTag{
+		{Name: "latest", ManifestDigest: "sha256:0a1b"},
+		{Name: "v5.2.1", ManifestDigest: "sha256:0a1b"},
+		{Name: "v5.2.2", ManifestDigest: "sha256:2c3d"},
+		{Name: "v5.2.3", ManifestDigest: "sha256:6a7b"},
+		{Name: "pr-42", ManifestDigest: "sha256:4e5f"},
+	}
+
+	summaries := fetchQuayVulnerabilities(client, certsuiteRepository, tags, globs{"latest", "v*"})
+	scanned := QuayVulnerabilitySummary{
+		Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", ManifestDigest: "sha256:0a1b", Status: "scanned",
+		Critical: 1, High: 1, Medium: 1, Low: 1, Unknown: 1, Fixable: 2,
+	}
+	latest, release := scanned, scanned
+	latest.Tag, release.Tag = "latest", "v5.2.1"
+	assert.Equal(t, []QuayVulnerabilitySummary{latest, release, {
+		Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Tag: "v5.2.2", ManifestDigest: "sha256:2c3d", Status: "queued",
+	}, {
+		// A scan that cannot be fetched is recorded rather than failing the sync
+		Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Tag: "v5.2.3", ManifestDigest: "sha256:6a7b", Status: quayScanUnavailable,
+	}}, summaries)
+	assert.Equal(t, 5, latest.Total())
+	// Tags sharing a manifest are scanned once
+	assert.Equal(t, 1, scans)
+}
+
+func TestReplaceQuayVulnerabilities(t *testing.T) {
+	store := newSQLiteStore(t)
+	latest := QuayVulnerabilitySummary{Namespace: "redhat-best-practices-for-k8s", Repository: "certsuite", Tag: "latest",
+		ManifestDigest: "sha256:0a1b", Status: quayScanned, High: 3, Low: 2, Fixable: 4}
+
+	require.NoError(t, store.ReplaceQuayVulnerabilities("2024-11-26", certsuiteRepository, []QuayVulnerabilitySummary{latest}))
+	latest.High = 1
+	require.NoError(t, store.ReplaceQuayVulnerabilities("2024-11-26", certsuiteRepository, []QuayVulnerabilitySummary{latest}))
+
+	summaries, err := store.GetQuayVulnerabilities("2024-11-26", certsuiteRepository)
+	require.NoError(t, err)
+	assert.Equal(t, []QuayVulnerabilitySummary{latest}, summaries)
+}
@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Sources whose sync progress is tracked in the sync_state table.
+const (
+	QuaySource = "quay"
+	DciSource  = "dci"
+)
+
+// IncrementalWindow returns the window from the source's watermark, moved back by overlap
+// to pick up late-arriving data, up to fallback.Until. Sources without a watermark use fallback.
+func IncrementalWindow(store Store, source string, overlap time.Duration, fallback Window) (Window, error) {
+	watermark, found, err := store.GetWatermark(source)
+	if err != nil {
+		return Window{}, fmt.Errorf("failed to read %s watermark: %w", source, err)
+	}
+	if !found {
+		log.Printf("No %s watermark yet, syncing %s", source, fallback)
+		return fallback, nil
+	}
+
+	since := watermark.Add(-overlap)
+	if !since.Before(fallback.Until) {
+		since = fallback.Until.Add(-overlap)
+	}
+	window := Window{Since: since, Until: fallback.Until}
+	log.Printf("Syncing %s incrementally from watermark %s: %s", source, watermark.Format(time.RFC3339), window)
+	return window, nil
+}
+
+// advanceWatermark records latest as the watermark of source, unless no data was ingested.
+func advanceWatermark(store Store, source string, latest time.Time) error {
+	if latest.IsZero() {
+		return nil
+	}
+	if err := store.AdvanceWatermark(source, latest); err != nil {
+		return fmt.Errorf("failed to advance %s watermark: %w", source, err)
+	}
+	return nil
+}
+
+func (s *sqlStore) GetWatermark(source string) (time.Time, bool, error) {
+	var watermark dbTime
+	err := s.db.QueryRow(s.dialect.rebind(`SELECT watermark FROM sync_state WHERE source = ?;`), source).Scan(&watermark)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return watermark.UTC(), true, nil
+}
+
+func (s *sqlStore) AdvanceWatermark(source string, watermark time.Time) error {
+	newWatermark := s.dialect.excluded("watermark")
+	query := s.dialect.upsertQuery("sync_state",
+		[]string{"source", "watermark", "updated_at"},
+		[]string{"source"},
+		fmt.Sprintf("watermark = CASE WHEN sync_state.watermark > %s THEN sync_state.watermark ELSE %s END", newWatermark, newWatermark),
+		"updated_at = "+s.dialect.excluded("updated_at"),
+	)
+	_, err := s.db.Exec(query, source, watermark.UTC().Format(dbTimestampFormat), time.Now().UTC().Format(dbTimestampFormat))
+	return err
+}
@@ -0,0 +1,39 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrementalWindow(t *testing.T) {
+	store := newSQLiteStore(t)
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	fallback := Window{Since: now.AddDate(0, 0, -7), Until: now}
+
+	// Without a watermark the fallback window is used.
+	window, err := IncrementalWindow(store, DciSource, time.Hour, fallback)
+	require.NoError(t, err)
+	assert.Equal(t, fallback, window)
+
+	// The watermark only moves forward.
+	watermark := time.Date(2024, 3, 14, 18, 0, 0, 0, time.UTC)
+	require.NoError(t, store.AdvanceWatermark(DciSource, watermark))
+	require.NoError(t, store.AdvanceWatermark(DciSource, watermark.AddDate(0, 0, -3)))
+
+	stored, found, err := store.GetWatermark(DciSource)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, watermark, stored)
+
+	window, err = IncrementalWindow(store, DciSource, time.Hour, fallback)
+	require.NoError(t, err)
+	assert.Equal(t, Window{Since: watermark.Add(-time.Hour), Until: now}, window)
+
+	// Other sources keep their own watermark.
+	_, found, err = store.GetWatermark(QuaySource)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/config"
+)
+
+// windowDateFormat is the layout of the --since and --until dates.
+const windowDateFormat = "2006-01-02"
+
+// Window is the half-open time range [Since, Until) covered by a sync run.
+type Window struct {
+	Since time.Time
+	Until time.Time
+}
+
+// String formats the window for logs.
+func (w Window) String() string {
+	return fmt.Sprintf("[%s, %s)", w.Since.Format(time.RFC3339), w.Until.Format(time.RFC3339))
+}
+
+// Contains reports whether t falls inside the window.
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Since) && t.Before(w.Until)
+}
+
+// daysBack is the number of whole days between the start of the window and now,
+// which is how far back the DCI client must page to reach it.
+func (w Window) daysBack(now time.Time) int {
+	return int(math.Ceil(now.Sub(w.Since).Hours() / 24))
+}
+
+// LastDays returns the window of the last days days ending at now.
+func LastDays(days int, now time.Time) (Window, error) {
+	if days <= 0 {
+		return Window{}, fmt.Errorf("number of days must be positive, got %d", days)
+	}
+	return Window{Since: now.AddDate(0, 0, -days), Until: now}, nil
+}
+
+// ResolveWindow builds the sync window from the configuration: the Since/Until dates
+// when either is set, otherwise the last NumDays days ending at now.
+func ResolveWindow(cfg config.Config, now time.Time) (Window, error) {
+	if cfg.Since == "" && cfg.Until == "" {
+		return LastDays(cfg.NumDays, now)
+	}
+	if cfg.Since == "" {
+		return Window{}, fmt.Errorf("an until date requires a since date")
+	}
+
+	since, err := time.Parse(windowDateFormat, cfg.Since)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid since date %q, expected YYYY-MM-DD: %w", cfg.Since, err)
+	}
+	until := now
+	if cfg.Until != "" {
+		until, err = time.Parse(windowDateFormat, cfg.Until)
+		if err != nil {
+			return Window{}, fmt.Errorf("invalid until date %q, expected YYYY-MM-DD: %w", cfg.Until, err)
+		}
+	}
+	if !since.Before(until) {
+		return Window{}, fmt.Errorf("since date %s must be before until date %s", cfg.Since, until.Format(windowDateFormat))
+	}
+	return Window{Since: since, Until: until}, nil
+}
@@ -0,0 +1,72 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/redhat-best-practices-for-k8s/certsuite-overview/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveWindow(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		cfg           config.Config
+		expected      Window
+		expectedError bool
+	}{
+		{
+			name:     "Last NumDays days",
+			cfg:      config.Config{NumDays: 1},
+			expected: Window{Since: time.Date(2024, 3, 14, 10, 30, 0, 0, time.UTC), Until: now},
+		},
+		{
+			name: "Since and until",
+			cfg:  config.Config{NumDays: 7, Since: "2024-01-01", Until: "2024-02-01"},
+			expected: Window{
+				Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Until: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "Since until now",
+			cfg:      config.Config{Since: "2024-03-01"},
+			expected: Window{Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Until: now},
+		},
+		{
+			name:          "Until without since",
+			cfg:           config.Config{Until: "2024-02-01"},
+			expectedError: true,
+		},
+		{
+			name:          "Since after until",
+			cfg:           config.Config{Since: "2024-02-01", Until: "2024-01-01"},
+			expectedError: true,
+		},
+		{
+			name:          "Invalid date",
+			cfg:           config.Config{Since: "01/01/2024"},
+			expectedError: true,
+		},
+		{
+			name:          "No days",
+			cfg:           config.Config{NumDays: 0},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			window, err := ResolveWindow(tc.cfg, now)
+
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, window)
+		})
+	}
+}